@@ -0,0 +1,57 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Take backups of collections",
+		Long: `Takes incremental backup points of collections. With --backup-mode full, self-contained full
+snapshots named <name>_full_<yyyyMMddHHmmss> are taken instead, e.g. for offsite archival or solr
+versions without the incremental format. Solr cannot list or delete full backups, so they are
+listed with their timestamp as backup id and pruned from the backup location, which must be
+mounted to this tool at the same path. Full backups stored in a --repository are listed from
+the journal and kept by prune, as they cannot be deleted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveCollections(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveAliases(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			return solrbackup.BackupAll(cmd.Context(), client, config)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
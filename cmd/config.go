@@ -0,0 +1,436 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	klog "k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+var (
+	// collectionDefaults and collectionOverrides are read from the defaults block and
+	// collection entries of the config file.
+	collectionDefaults  solrbackup.CollectionConfig
+	collectionOverrides map[string]solrbackup.CollectionConfig
+
+	// commandLineFlags are the flags given on the command line, they take precedence
+	// over the config file.
+	commandLineFlags map[string]bool
+
+	// retentionFlags are the flags of the global retention policy.
+	retentionFlags = []string{"retention-days", "keep-last", "keep-daily", "keep-weekly", "keep-monthly", "keep-yearly", "keep-minimum"}
+)
+
+// collectionEntry is a collection entry of the config file, either a collection name
+// or a map of its name and overridden settings:
+//
+//	defaults:
+//	  retention: {days: 7}
+//	collections:
+//	  - products
+//	  - name: logs
+//	    location: /backups/logs
+//	    repository: s3
+//	    backupMode: full
+//	    retention: {keepLast: 3, weekly: 4}
+//	    tags: [weekly]
+//	    group: big
+//	    restore: {replicationFactor: 2}
+type collectionEntry struct {
+	Name                        string
+	solrbackup.CollectionConfig `mapstructure:",squash"`
+}
+
+// collectionNameHook decodes collection entries given only by name.
+func collectionNameHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if from.Kind() == reflect.String && to == reflect.TypeOf(collectionEntry{}) {
+		return map[string]interface{}{"name": data}, nil
+	}
+
+	return data, nil
+}
+
+// errorUnused rejects unknown keys, so mistyped settings are not silently ignored.
+func errorUnused(c *mapstructure.DecoderConfig) {
+	c.ErrorUnused = true
+}
+
+// checkRetention rejects a retention block which keeps no backup points, as it
+// replaces the global retention policy.
+func checkRetention(policy *solrbackup.RetentionPolicy) error {
+	if policy != nil && policy.IsZero() {
+		return errors.New("retention keeps no backup points, at least one rule is required")
+	}
+
+	return nil
+}
+
+// loadCollectionConfig reads the defaults block and collection entries with
+// overridden settings, and replaces the entries with their names so they can be
+// set to the collections flag.
+func loadCollectionConfig(v *viper.Viper) error {
+	collectionDefaults = solrbackup.CollectionConfig{}
+	collectionOverrides = make(map[string]solrbackup.CollectionConfig)
+
+	if v.IsSet("defaults") {
+		if err := v.UnmarshalKey("defaults", &collectionDefaults, errorUnused); err != nil {
+			return fmt.Errorf("invalid defaults: %v", err)
+		}
+
+		if err := checkRetention(collectionDefaults.Retention); err != nil {
+			return fmt.Errorf("invalid defaults: %v", err)
+		}
+	}
+
+	items, ok := v.Get("collections").([]interface{})
+	if !ok {
+		return nil
+	}
+
+	structured := false
+	for _, item := range items {
+		if _, ok := item.(string); !ok {
+			structured = true
+		}
+	}
+
+	if !structured {
+		return nil
+	}
+
+	var entries []collectionEntry
+
+	if err := v.UnmarshalKey("collections", &entries, viper.DecodeHook(collectionNameHook), errorUnused); err != nil {
+		return fmt.Errorf("invalid collections: %v", err)
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.Name == "" {
+			return errors.New("invalid collections: entry without name")
+		}
+
+		if _, ok := collectionOverrides[entry.Name]; ok {
+			return fmt.Errorf("invalid collections: %s is given more than once", entry.Name)
+		}
+
+		if err := checkRetention(entry.Retention); err != nil {
+			return fmt.Errorf("invalid collections: %s: %v", entry.Name, err)
+		}
+
+		names = append(names, entry.Name)
+		collectionOverrides[entry.Name] = entry.CollectionConfig
+	}
+
+	v.Set("collections", names)
+
+	return nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringP("solr-endpoint", "e", "http://localhost:8983", "solr endpoint (scheme://host:port)")
+	rootCmd.PersistentFlags().StringP("location", "l", "", "backup location at solr nodes")
+	rootCmd.PersistentFlags().String("repository", "", "backup repository of solr.xml (default solr default repository)")
+	rootCmd.PersistentFlags().String("backup-mode", solrbackup.BackupModeIncremental, "incremental backup points or full snapshots named <name>_full_<timestamp>")
+	rootCmd.PersistentFlags().StringSliceP("collections", "c", []string{}, "collections to operate on")
+	rootCmd.PersistentFlags().StringSlice("include", []string{}, "discover cluster collections matching glob (or re:regex) patterns")
+	rootCmd.PersistentFlags().StringSlice("exclude", []string{}, "skip discovered collections matching glob (or re:regex) patterns")
+	rootCmd.PersistentFlags().StringSlice("tags", []string{}, "only operate on collections having any of the schedule tags")
+	rootCmd.PersistentFlags().Duration("request-timeout", time.Minute, "timeout of a single solr request")
+	rootCmd.PersistentFlags().String("username", "", "basic auth username")
+	rootCmd.PersistentFlags().String("password", "", "basic auth password")
+	rootCmd.PersistentFlags().String("token", "", "bearer token (e.g. jwt)")
+	rootCmd.PersistentFlags().String("token-file", "", "file to read bearer token from on each request")
+	rootCmd.PersistentFlags().String("ca-file", "", "ca bundle to verify solr certificates")
+	rootCmd.PersistentFlags().String("cert-file", "", "client certificate for mutual tls")
+	rootCmd.PersistentFlags().String("key-file", "", "client certificate key for mutual tls")
+	rootCmd.PersistentFlags().String("tls-server-name", "", "server name to verify solr certificates against")
+	rootCmd.PersistentFlags().Bool("insecure-skip-verify", false, "do not verify solr certificates (insecure)")
+
+	retry := solrbackup.DefaultRetryPolicy()
+	rootCmd.PersistentFlags().Int("retry-max-attempts", retry.MaxAttempts, "max attempts of a solr request, 1 disables retries")
+	rootCmd.PersistentFlags().Duration("retry-initial-backoff", retry.InitialBackoff, "wait before first retry")
+	rootCmd.PersistentFlags().Duration("retry-max-backoff", retry.MaxBackoff, "max wait between retries")
+	rootCmd.PersistentFlags().Float64("retry-jitter", retry.Jitter, "randomization fraction of retry waits")
+	rootCmd.PersistentFlags().IntSlice("retry-status-codes", retry.RetryableStatusCodes, "http status codes to retry")
+
+	rootCmd.PersistentFlags().Duration("backup-timeout", 6*time.Hour, "max wait for a backup to finish, 0 waits forever")
+	rootCmd.PersistentFlags().Duration("restore-timeout", 6*time.Hour, "max wait for a restore to finish, 0 waits forever")
+	rootCmd.PersistentFlags().Duration("delete-timeout", time.Hour, "max wait for a backup delete to finish, 0 waits forever")
+	rootCmd.PersistentFlags().Duration("poll-interval", 5*time.Second, "initial interval of async request status polls")
+	rootCmd.PersistentFlags().Duration("max-poll-interval", time.Minute, "max interval of async request status polls")
+	rootCmd.PersistentFlags().Float64("poll-backoff", 1.5, "poll interval multiplier after each poll")
+	rootCmd.PersistentFlags().Int("concurrency", 1, "number of collections processed in parallel")
+	rootCmd.PersistentFlags().String("request-id-scope", "", "scope put into async request ids (default host name)")
+	rootCmd.PersistentFlags().String("journal", defaultJournalFile(), "journal file of submitted async requests, empty disables journal")
+}
+
+// defaultJournalFile returns the journal in the home directory of the user, or no
+// journal when there is no home directory, e.g. in a scratch container.
+func defaultJournalFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".solr-backup", "journal.jsonl")
+}
+
+// flagDefaults returns the defaults block without the settings which are given by
+// flags on the command line, so they apply to collections without own settings.
+func flagDefaults(defaults solrbackup.CollectionConfig) solrbackup.CollectionConfig {
+	if commandLineFlags["location"] {
+		defaults.Location = ""
+	}
+
+	if commandLineFlags["repository"] {
+		defaults.Repository = ""
+	}
+
+	if commandLineFlags["backup-mode"] {
+		defaults.BackupMode = ""
+	}
+
+	for _, name := range retentionFlags {
+		if commandLineFlags[name] {
+			defaults.Retention = nil
+		}
+	}
+
+	return defaults
+}
+
+// backupModes returns the backup modes given by flags and the config file.
+func backupModes(config solrbackup.Config) []string {
+	modes := []string{config.BackupMode}
+
+	if config.Defaults.BackupMode != "" {
+		modes = append(modes, config.Defaults.BackupMode)
+	}
+
+	for _, override := range config.Overrides {
+		if override.BackupMode != "" {
+			modes = append(modes, override.BackupMode)
+		}
+	}
+
+	return modes
+}
+
+// overridesLocation tells whether any collection entry of the config file has a
+// backup location.
+func overridesLocation(config solrbackup.Config) bool {
+	for _, override := range config.Overrides {
+		if override.Location != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configFromFlags returns configuration of commands which operate on collections.
+func configFromFlags(cmd *cobra.Command) (solrbackup.Config, error) {
+	config, err := clusterConfigFromFlags(cmd)
+	if err != nil {
+		return config, err
+	}
+
+	// locations of selected collections are checked once they are resolved
+	if config.Location == "" && config.Defaults.Location == "" && !overridesLocation(config) {
+		return config, errors.New("backup location is required")
+	}
+
+	if len(config.Collections) == 0 && len(config.Include) == 0 && len(config.Exclude) == 0 {
+		return config, errors.New("at least one collection or include/exclude pattern is required")
+	}
+
+	return config, nil
+}
+
+// clusterConfigFromFlags returns configuration of commands which only need a
+// connection to solr.
+func clusterConfigFromFlags(cmd *cobra.Command) (solrbackup.Config, error) {
+	var config solrbackup.Config
+	var err error
+
+	if config.SolrEndpoint, err = cmd.Flags().GetString("solr-endpoint"); err != nil {
+		return config, err
+	}
+
+	if config.Location, err = cmd.Flags().GetString("location"); err != nil {
+		return config, err
+	}
+
+	if config.Repository, err = cmd.Flags().GetString("repository"); err != nil {
+		return config, err
+	}
+
+	if config.BackupMode, err = cmd.Flags().GetString("backup-mode"); err != nil {
+		return config, err
+	}
+
+	if config.Collections, err = cmd.Flags().GetStringSlice("collections"); err != nil {
+		return config, err
+	}
+
+	if config.Include, err = cmd.Flags().GetStringSlice("include"); err != nil {
+		return config, err
+	}
+
+	if config.Exclude, err = cmd.Flags().GetStringSlice("exclude"); err != nil {
+		return config, err
+	}
+
+	if config.Tags, err = cmd.Flags().GetStringSlice("tags"); err != nil {
+		return config, err
+	}
+
+	config.Defaults = flagDefaults(collectionDefaults)
+	config.Overrides = collectionOverrides
+
+	if config.RequestTimeout, err = cmd.Flags().GetDuration("request-timeout"); err != nil {
+		return config, err
+	}
+
+	if config.Username, err = cmd.Flags().GetString("username"); err != nil {
+		return config, err
+	}
+
+	if config.Password, err = cmd.Flags().GetString("password"); err != nil {
+		return config, err
+	}
+
+	if config.Token, err = cmd.Flags().GetString("token"); err != nil {
+		return config, err
+	}
+
+	if config.TokenFile, err = cmd.Flags().GetString("token-file"); err != nil {
+		return config, err
+	}
+
+	if config.CAFile, err = cmd.Flags().GetString("ca-file"); err != nil {
+		return config, err
+	}
+
+	if config.CertFile, err = cmd.Flags().GetString("cert-file"); err != nil {
+		return config, err
+	}
+
+	if config.KeyFile, err = cmd.Flags().GetString("key-file"); err != nil {
+		return config, err
+	}
+
+	if config.ServerName, err = cmd.Flags().GetString("tls-server-name"); err != nil {
+		return config, err
+	}
+
+	if config.InsecureSkipVerify, err = cmd.Flags().GetBool("insecure-skip-verify"); err != nil {
+		return config, err
+	}
+
+	config.Retry = solrbackup.DefaultRetryPolicy()
+
+	if config.Retry.MaxAttempts, err = cmd.Flags().GetInt("retry-max-attempts"); err != nil {
+		return config, err
+	}
+
+	if config.Retry.InitialBackoff, err = cmd.Flags().GetDuration("retry-initial-backoff"); err != nil {
+		return config, err
+	}
+
+	if config.Retry.MaxBackoff, err = cmd.Flags().GetDuration("retry-max-backoff"); err != nil {
+		return config, err
+	}
+
+	if config.Retry.Jitter, err = cmd.Flags().GetFloat64("retry-jitter"); err != nil {
+		return config, err
+	}
+
+	if config.Retry.RetryableStatusCodes, err = cmd.Flags().GetIntSlice("retry-status-codes"); err != nil {
+		return config, err
+	}
+
+	if config.BackupTimeout, err = cmd.Flags().GetDuration("backup-timeout"); err != nil {
+		return config, err
+	}
+
+	if config.RestoreTimeout, err = cmd.Flags().GetDuration("restore-timeout"); err != nil {
+		return config, err
+	}
+
+	if config.DeleteTimeout, err = cmd.Flags().GetDuration("delete-timeout"); err != nil {
+		return config, err
+	}
+
+	if config.PollInterval, err = cmd.Flags().GetDuration("poll-interval"); err != nil {
+		return config, err
+	}
+
+	if config.MaxPollInterval, err = cmd.Flags().GetDuration("max-poll-interval"); err != nil {
+		return config, err
+	}
+
+	if config.PollBackoff, err = cmd.Flags().GetFloat64("poll-backoff"); err != nil {
+		return config, err
+	}
+
+	if config.Concurrency, err = cmd.Flags().GetInt("concurrency"); err != nil {
+		return config, err
+	}
+
+	if config.RequestIdScope, err = cmd.Flags().GetString("request-id-scope"); err != nil {
+		return config, err
+	}
+
+	if config.JournalFile, err = cmd.Flags().GetString("journal"); err != nil {
+		return config, err
+	}
+
+	if cmd.Flags().Lookup("retention-days") != nil {
+		if config.RetaintionDays, err = cmd.Flags().GetInt("retention-days"); err != nil {
+			return config, err
+		}
+	}
+
+	if cmd.Flags().Lookup("create-alias") != nil {
+		if config.RestoreAliases, err = cmd.Flags().GetBool("create-alias"); err != nil {
+			return config, err
+		}
+	}
+
+	if config.SolrEndpoint == "" {
+		return config, errors.New("solr endpoint is required")
+	}
+
+	for _, mode := range backupModes(config) {
+		if mode != solrbackup.BackupModeIncremental && mode != solrbackup.BackupModeFull {
+			return config, fmt.Errorf("invalid backup mode %q, expected %s or %s", mode, solrbackup.BackupModeIncremental, solrbackup.BackupModeFull)
+		}
+	}
+
+	klog.V(5).Infof("config: endpoint=%v location=%v collections=%v", config.SolrEndpoint, config.Location, config.Collections)
+
+	return config, nil
+}
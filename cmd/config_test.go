@@ -0,0 +1,103 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Config Tests", func() {
+	Context("Config File Precedence Tests", func() {
+
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		// run executes a command with the config file and returns configuration of it
+		run := func(args ...string) solrbackup.Config {
+			configFile := filepath.Join(dir, "config.yaml")
+			Expect(ioutil.WriteFile(configFile, []byte(`
+defaults:
+  location: /from-config
+  repository: cfgrepo
+  retention: {keepLast: 2}
+collections:
+  - products
+`), 0644)).To(BeNil())
+
+			var config solrbackup.Config
+			testCmd := &cobra.Command{
+				Use: "test-config",
+				RunE: func(cmd *cobra.Command, args []string) error {
+					var err error
+					config, err = configFromFlags(cmd)
+					return err
+				},
+			}
+			testCmd.Flags().Int("keep-last", 0, "")
+			testCmd.Flags().IntP("retention-days", "r", 7, "")
+
+			// flags keep their values between executions
+			for _, name := range []string{"location", "repository"} {
+				f := rootCmd.PersistentFlags().Lookup(name)
+				Expect(f.Value.Set(f.DefValue)).To(BeNil())
+				f.Changed = false
+			}
+
+			rootCmd.AddCommand(testCmd)
+			defer rootCmd.RemoveCommand(testCmd)
+
+			rootCmd.SetArgs(append([]string{"test-config", "--config", configFile}, args...))
+			Expect(rootCmd.Execute()).To(BeNil(), "command returns error")
+
+			return config
+		}
+
+		Describe("Test defaults without flags", func() {
+			It("Should be taken from config file", func() {
+				config := run()
+				Expect(config.Collections).To(Equal([]string{"products"}))
+				Expect(config.Defaults.Location).To(Equal("/from-config"))
+				Expect(config.Defaults.Repository).To(Equal("cfgrepo"))
+				Expect(*config.Defaults.Retention).To(Equal(solrbackup.RetentionPolicy{KeepLast: 2}))
+			})
+		})
+
+		Describe("Test defaults with flags", func() {
+			It("Should be overridden by flags on the command line", func() {
+				config := run("--location", "/from-flag", "--repository", "flagrepo", "--retention-days", "0", "--keep-last", "5")
+				Expect(config.Location).To(Equal("/from-flag"))
+				Expect(config.Repository).To(Equal("flagrepo"))
+				Expect(config.Defaults.Location).To(BeEmpty())
+				Expect(config.Defaults.Repository).To(BeEmpty())
+				Expect(config.Defaults.Retention).To(BeNil())
+			})
+		})
+
+	})
+})
@@ -0,0 +1,47 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List backup points of collections",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveCollections(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			return solrbackup.BackupListAll(cmd.Context(), client, config)
+		},
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
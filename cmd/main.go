@@ -15,6 +15,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/spf13/cobra"
@@ -23,16 +24,20 @@ import (
 	"github.com/spf13/viper"
 	klog "k8s.io/klog/v2"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
 var (
 	rootCmd = &cobra.Command{
-		Use:   "solr-backup",
-		Short: "A solr backup tool",
-		Long:  `A solr cloud backup tool for kube cronjobs`,
+		Use:           "solr-backup",
+		Short:         "A solr backup tool",
+		Long:          `A solr cloud backup tool for kube cronjobs`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return initializeConfig(cmd)
 		},
@@ -110,7 +115,10 @@ func init() {
 }
 
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return rootCmd.ExecuteContext(ctx)
 }
 
 func initializeConfig(cmd *cobra.Command) error {
@@ -137,6 +145,13 @@ func initializeConfig(cmd *cobra.Command) error {
 	if configFile != "" {
 		klog.V(6).Infof("a config file given as parameter: %v", configFile)
 		if r, err := os.Open(configFile); err == nil {
+			// viper cannot decode the file without knowing its type
+			configType := strings.TrimPrefix(filepath.Ext(configFile), ".")
+			if configType == "" {
+				configType = "yaml"
+			}
+			v.SetConfigType(configType)
+
 			err = v.MergeConfig(r)
 			if err != nil {
 				klog.V(6).Error(err, "cannot merge config file")
@@ -149,18 +164,36 @@ func initializeConfig(cmd *cobra.Command) error {
 		}
 	}
 
+	if err := loadCollectionConfig(v); err != nil {
+		return err
+	}
+
 	v.SetEnvPrefix(strings.ToUpper(progName))
 	v.AutomaticEnv()
 
+	commandLineFlags = make(map[string]bool)
+
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			commandLineFlags[f.Name] = true
+		}
+
 		if strings.Contains(f.Name, "-") {
 			envVarSuffix := strings.ToUpper(strings.ReplaceAll(f.Name, "-", "_"))
 			v.BindEnv(f.Name, fmt.Sprintf("%s_%s", strings.ToUpper(progName), envVarSuffix))
 		}
 
 		if !f.Changed && v.IsSet(f.Name) {
-			val := v.Get(f.Name)
-			cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
+			if strings.HasSuffix(f.Value.Type(), "Slice") {
+				cmd.Flags().Set(f.Name, strings.Join(v.GetStringSlice(f.Name), ","))
+			} else if strings.HasSuffix(f.Value.Type(), "Array") {
+				for _, val := range v.GetStringSlice(f.Name) {
+					cmd.Flags().Set(f.Name, val)
+				}
+			} else {
+				val := v.Get(f.Name)
+				cmd.Flags().Set(f.Name, fmt.Sprintf("%v", val))
+			}
 		}
 	})
 	klog.V(6).Infof("config initialized")
@@ -170,5 +203,7 @@ func initializeConfig(cmd *cobra.Command) error {
 func main() {
 	if err := Execute(); err != nil {
 		klog.Errorf("backup command failed err=%v", err)
+		klog.Flush()
+		os.Exit(1)
 	}
 }
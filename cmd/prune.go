@@ -0,0 +1,190 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"errors"
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+var (
+	pruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete backup points which are not kept by the retention policy",
+		Long: `Deletes backup points which are not kept by any retention rule. Backup points started within
+--retention-days are kept, and grandfather-father-son rules keep the newest backup point of
+the latest --keep-daily days, --keep-weekly weeks, --keep-monthly months and --keep-yearly
+years. --keep-last keeps the newest backup points and the newest --keep-minimum backup points
+are never deleted, even when all of them are old. Set --retention-days to 0 to use only the
+other rules.
+
+The decision of every backup point and the rules keeping it are printed before anything is
+deleted. --dry-run only prints them; a plan printed with --dry-run --output json can be saved
+and applied later exactly as it is with --plan-file. Collections which cannot be planned are
+reported as failed and left untouched, the others are still pruned.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			planFile, err := cmd.Flags().GetString("plan-file")
+			if err != nil {
+				return err
+			}
+
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				return err
+			}
+
+			output, err := cmd.Flags().GetString("output")
+			if err != nil {
+				return err
+			}
+
+			if planFile != "" {
+				return applyPlanFile(cmd, planFile, dryRun, output)
+			}
+
+			config, err := configFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			if config.Retention, err = retentionPolicyFromFlags(cmd); err != nil {
+				return err
+			}
+
+			if config.RetaintionDays < 0 {
+				return errors.New("retention days should not be negative")
+			}
+
+			if config.Retention.IsZero() && config.RetaintionDays == 0 {
+				return errors.New("retention policy keeps no backup points, at least one rule is required")
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveCollections(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			// collections which cannot be planned are in the plan with their
+			// error, the others are still pruned
+			plan, planErr := solrbackup.PlanPrune(cmd.Context(), client, config)
+
+			if err := solrbackup.RenderPrunePlan(plan, output); err != nil {
+				return err
+			}
+
+			if dryRun {
+				return planErr
+			}
+
+			return solrbackup.ApplyPrunePlan(cmd.Context(), client, config, plan)
+		},
+	}
+)
+
+// applyPlanFile applies a plan saved by a json dry run, retention flags and
+// collection selection are not used then.
+func applyPlanFile(cmd *cobra.Command, planFile string, dryRun bool, output string) error {
+	config, err := clusterConfigFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(planFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	plan, err := solrbackup.ReadPrunePlan(f)
+	if err != nil {
+		return err
+	}
+
+	if config.Location == "" {
+		config.Location = plan.Location
+	}
+
+	if err := solrbackup.RenderPrunePlan(plan, output); err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	client, err := solrbackup.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	return solrbackup.ApplyPrunePlan(cmd.Context(), client, config, plan)
+}
+
+func retentionPolicyFromFlags(cmd *cobra.Command) (solrbackup.RetentionPolicy, error) {
+	var policy solrbackup.RetentionPolicy
+	var err error
+
+	if policy.KeepLast, err = cmd.Flags().GetInt("keep-last"); err != nil {
+		return policy, err
+	}
+
+	if policy.Daily, err = cmd.Flags().GetInt("keep-daily"); err != nil {
+		return policy, err
+	}
+
+	if policy.Weekly, err = cmd.Flags().GetInt("keep-weekly"); err != nil {
+		return policy, err
+	}
+
+	if policy.Monthly, err = cmd.Flags().GetInt("keep-monthly"); err != nil {
+		return policy, err
+	}
+
+	if policy.Yearly, err = cmd.Flags().GetInt("keep-yearly"); err != nil {
+		return policy, err
+	}
+
+	if policy.Minimum, err = cmd.Flags().GetInt("keep-minimum"); err != nil {
+		return policy, err
+	}
+
+	for _, value := range []int{policy.KeepLast, policy.Daily, policy.Weekly, policy.Monthly, policy.Yearly, policy.Minimum} {
+		if value < 0 {
+			return policy, errors.New("retention rules should not be negative")
+		}
+	}
+
+	return policy, nil
+}
+
+func init() {
+	pruneCmd.Flags().IntP("retention-days", "r", 7, "keep backup points started within given days")
+	pruneCmd.Flags().Int("keep-last", 0, "keep given number of newest backup points")
+	pruneCmd.Flags().Int("keep-daily", 0, "keep newest backup point of given number of days")
+	pruneCmd.Flags().Int("keep-weekly", 0, "keep newest backup point of given number of weeks")
+	pruneCmd.Flags().Int("keep-monthly", 0, "keep newest backup point of given number of months")
+	pruneCmd.Flags().Int("keep-yearly", 0, "keep newest backup point of given number of years")
+	pruneCmd.Flags().Int("keep-minimum", 0, "never delete given number of newest backup points")
+	pruneCmd.Flags().Bool("dry-run", false, "only print which backup points would be kept or deleted")
+	pruneCmd.Flags().StringP("output", "o", "table", "format of the prune plan (table or json)")
+	pruneCmd.Flags().String("plan-file", "", "apply prune plan saved from a json dry run")
+
+	rootCmd.AddCommand(pruneCmd)
+}
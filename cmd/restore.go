@@ -0,0 +1,283 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	restoreCmd = &cobra.Command{
+		Use:   "restore",
+		Short: "Restore collections from their latest backup",
+		Long: `Restores collections in place from their latest backup. A single collection can be restored
+into a new collection with --target. An older backup point can be selected with --backup-id or
+--before (e.g. 2022-10-01T00:00Z).
+
+With --blue-green, backups are restored into new <name>_restore_<timestamp> collections which
+replace live collections behind an alias only after all their replicas are active and the
+validation query matches, so live data is never overwritten.
+
+Restoring over a collection which has documents needs --force or an interactive confirmation,
+and with --safety-backup the collection is first backed up as <name>_before_restore. Only the
+newest safety backup is kept, older ones are deleted once a new one is taken. Full safety
+backups stored in a --repository are not deleted.
+
+With --verify, restored collections are checked like the verify command does before aliases
+are pointed to them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			opts, err := restoreOptionsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveCollections(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			if len(config.Collections) == 0 {
+				return errors.New("no collection is selected to restore")
+			}
+
+			if config, err = solrbackup.ResolveAliases(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			if verify, err := cmd.Flags().GetBool("verify"); err != nil {
+				return err
+			} else if verify {
+				verifyOpts, err := verifyOptionsFromFlags(cmd)
+				if err != nil {
+					return err
+				}
+
+				opts.Verify = &verifyOpts
+			}
+
+			if opts.Force, err = cmd.Flags().GetBool("force"); err != nil {
+				return err
+			}
+
+			if opts.SafetyBackup, err = cmd.Flags().GetBool("safety-backup"); err != nil {
+				return err
+			}
+
+			if isTerminal(os.Stdin) {
+				opts.Confirm = confirmRestore
+			}
+
+			if blueGreen, err := cmd.Flags().GetBool("blue-green"); err != nil {
+				return err
+			} else if blueGreen {
+				bgOpts, err := blueGreenOptionsFromFlags(cmd, opts)
+				if err != nil {
+					return err
+				}
+
+				return solrbackup.RestoreAllBlueGreen(cmd.Context(), client, config, bgOpts)
+			}
+
+			return solrbackup.RestoreAll(cmd.Context(), client, config, opts)
+		},
+	}
+)
+
+func restoreOptionsFromFlags(cmd *cobra.Command) (solrbackup.RestoreOptions, error) {
+	var opts solrbackup.RestoreOptions
+	var err error
+
+	if opts.TargetCollection, err = cmd.Flags().GetString("target"); err != nil {
+		return opts, err
+	}
+
+	if cmd.Flags().Changed("backup-id") {
+		backupId, err := cmd.Flags().GetInt64("backup-id")
+		if err != nil {
+			return opts, err
+		}
+
+		opts.BackupId = &backupId
+	}
+
+	before, err := cmd.Flags().GetString("before")
+	if err != nil {
+		return opts, err
+	}
+
+	if before != "" {
+		if opts.BackupId != nil {
+			return opts, errors.New("backup id and before cannot be used together")
+		}
+
+		if opts.Before, err = parseTimestamp(before); err != nil {
+			return opts, err
+		}
+	}
+
+	if opts.ConfigName, err = cmd.Flags().GetString("config-name"); err != nil {
+		return opts, err
+	}
+
+	if opts.ReplicationFactor, err = cmd.Flags().GetInt("replication-factor"); err != nil {
+		return opts, err
+	}
+
+	if opts.NrtReplicas, err = cmd.Flags().GetInt("nrt-replicas"); err != nil {
+		return opts, err
+	}
+
+	if opts.TlogReplicas, err = cmd.Flags().GetInt("tlog-replicas"); err != nil {
+		return opts, err
+	}
+
+	if opts.PullReplicas, err = cmd.Flags().GetInt("pull-replicas"); err != nil {
+		return opts, err
+	}
+
+	if opts.MaxShardsPerNode, err = cmd.Flags().GetInt("max-shards-per-node"); err != nil {
+		return opts, err
+	}
+
+	if opts.CreateNodeSet, err = cmd.Flags().GetStringSlice("create-node-set"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+func blueGreenOptionsFromFlags(cmd *cobra.Command, restoreOpts solrbackup.RestoreOptions) (solrbackup.BlueGreenOptions, error) {
+	opts := solrbackup.BlueGreenOptions{RestoreOptions: restoreOpts}
+	var err error
+
+	if opts.TargetCollection != "" {
+		return opts, errors.New("target cannot be used with blue/green restore")
+	}
+
+	if opts.Alias, err = cmd.Flags().GetString("alias"); err != nil {
+		return opts, err
+	}
+
+	if opts.ValidationQuery, err = cmd.Flags().GetString("validation-query"); err != nil {
+		return opts, err
+	}
+
+	if opts.MinDocuments, err = cmd.Flags().GetInt64("min-documents"); err != nil {
+		return opts, err
+	}
+
+	if opts.ReadyTimeout, err = cmd.Flags().GetDuration("ready-timeout"); err != nil {
+		return opts, err
+	}
+
+	if opts.DeletePrevious, err = cmd.Flags().GetBool("delete-previous"); err != nil {
+		return opts, err
+	}
+
+	if opts.GracePeriod, err = cmd.Flags().GetDuration("grace-period"); err != nil {
+		return opts, err
+	}
+
+	return opts, nil
+}
+
+var confirmMu sync.Mutex
+
+// confirmRestore asks on the terminal whether the collection should be overwritten,
+// one collection at a time.
+func confirmRestore(plan solrbackup.RestorePlan) (bool, error) {
+	confirmMu.Lock()
+	defer confirmMu.Unlock()
+
+	fmt.Printf("collection %s has %d documents which will be replaced by %s\ncontinue? [y/N] ", plan.Collection, plan.Documents, plan)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes", nil
+}
+
+// isTerminal tells whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// parseTimestamp parses RFC 3339 timestamps, allowing seconds or the whole time
+// part to be omitted.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp %q, expected e.g. 2022-10-01T00:00Z", s)
+}
+
+func init() {
+	restoreCmd.Flags().Bool("create-alias", false, "point aliases to their restored collections")
+	restoreCmd.Flags().String("target", "", "collection to restore into (default in place)")
+	restoreCmd.Flags().Int64("backup-id", 0, "backup point to restore (default latest)")
+	restoreCmd.Flags().String("before", "", "restore latest backup point started before the timestamp")
+	restoreCmd.Flags().String("config-name", "", "configset of the restored collection")
+	restoreCmd.Flags().Int("replication-factor", 0, "replication factor of the restored collection")
+	restoreCmd.Flags().Int("nrt-replicas", 0, "nrt replicas of the restored collection")
+	restoreCmd.Flags().Int("tlog-replicas", 0, "tlog replicas of the restored collection")
+	restoreCmd.Flags().Int("pull-replicas", 0, "pull replicas of the restored collection")
+	restoreCmd.Flags().Int("max-shards-per-node", 0, "max shards per node of the restored collection")
+	restoreCmd.Flags().StringSlice("create-node-set", []string{}, "nodes to create the restored collection on")
+
+	restoreCmd.Flags().Bool("force", false, "restore over collections which have documents without confirmation")
+	restoreCmd.Flags().Bool("safety-backup", false, "back up collections before restoring over them")
+	restoreCmd.Flags().Bool("verify", false, "verify replica health and document counts of restored collections")
+	restoreCmd.Flags().StringArray("verify-query", []string{}, "query and the document count it should match, as <query>=<count>")
+	restoreCmd.Flags().Int64("count-tolerance", 0, "max difference between document count and count recorded at backup")
+
+	restoreCmd.Flags().Bool("blue-green", false, "restore into a new collection and switch alias to it")
+	restoreCmd.Flags().String("alias", "", "alias to switch on blue/green restore (default backup alias or name)")
+	restoreCmd.Flags().String("validation-query", "*:*", "query validating restored collection before alias switch")
+	restoreCmd.Flags().Int64("min-documents", 1, "min documents validation query should match")
+	restoreCmd.Flags().Duration("ready-timeout", 30*time.Minute, "max wait for replicas of restored collection to become active")
+	restoreCmd.Flags().Bool("delete-previous", false, "delete collections previously behind the alias")
+	restoreCmd.Flags().Duration("grace-period", 10*time.Minute, "wait before deleting previous collections")
+
+	rootCmd.AddCommand(restoreCmd)
+}
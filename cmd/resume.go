@@ -0,0 +1,57 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resumeCmd = &cobra.Command{
+		Use:   "resume",
+		Short: "Wait for async requests left behind by an interrupted run and clean them up",
+		Long: `Reads pending async requests from the journal (or lists them from solr when journal is disabled),
+waits for them to finish, reports their outcome and deletes their status at solr.
+
+A job sharing the journal may still poll its requests, so requests are only resumed once their
+job gave up waiting or they were last recorded longer ago than the timeout of their operation
+(--backup-timeout, --restore-timeout or --delete-timeout), or than --min-age when it is set.
+Requests of operations waited forever are only resumed with --min-age.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := clusterConfigFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			var opts solrbackup.ResumeOptions
+			if opts.MinAge, err = cmd.Flags().GetDuration("min-age"); err != nil {
+				return err
+			}
+
+			return solrbackup.Resume(cmd.Context(), client, config, opts)
+		},
+	}
+)
+
+func init() {
+	resumeCmd.Flags().Duration("min-age", 0, "resume requests last recorded longer ago, 0 uses their operation timeout")
+
+	rootCmd.AddCommand(resumeCmd)
+}
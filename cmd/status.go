@@ -0,0 +1,63 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var (
+	statusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Manage async request statuses stored at solr",
+	}
+
+	statusGCCmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Delete stale statuses of finished async requests of this tool",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := clusterConfigFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			var opts solrbackup.StatusGCOptions
+
+			if opts.OlderThan, err = cmd.Flags().GetDuration("older-than"); err != nil {
+				return err
+			}
+
+			if opts.DryRun, err = cmd.Flags().GetBool("dry-run"); err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			return solrbackup.StatusGC(cmd.Context(), client, opts)
+		},
+	}
+)
+
+func init() {
+	statusGCCmd.Flags().Duration("older-than", 24*time.Hour, "delete statuses of requests older than given duration")
+	statusGCCmd.Flags().Bool("dry-run", false, "only report statuses which would be deleted")
+
+	statusCmd.AddCommand(statusGCCmd)
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"fmt"
+	"github.com/mantis-software-company/go-solr-backup/internal/solrbackup"
+	"github.com/spf13/cobra"
+	"strconv"
+	"strings"
+)
+
+var (
+	verifyCmd = &cobra.Command{
+		Use:   "verify",
+		Short: "Verify collections against their latest backup",
+		Long: `Verifies that every shard of the collections has an active leader and all replicas are
+active, that they have as many documents as were counted when their latest backup was taken
+(recorded in the journal) and that --verify-query queries match their expected counts.
+
+Document counts are recorded before and after BACKUP runs and only kept when both are
+equal, backups of collections indexed meanwhile skip the count comparison. Use
+--count-tolerance to accept a difference to the recorded count.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := configFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			opts, err := verifyOptionsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			client, err := solrbackup.NewClient(config)
+			if err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveCollections(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			if config, err = solrbackup.ResolveAliases(cmd.Context(), client, config); err != nil {
+				return err
+			}
+
+			return solrbackup.VerifyAll(cmd.Context(), client, config, opts)
+		},
+	}
+)
+
+func verifyOptionsFromFlags(cmd *cobra.Command) (solrbackup.VerifyOptions, error) {
+	var opts solrbackup.VerifyOptions
+	var err error
+
+	queries, err := cmd.Flags().GetStringArray("verify-query")
+	if err != nil {
+		return opts, err
+	}
+
+	for _, query := range queries {
+		expected, err := parseQueryExpectation(query)
+		if err != nil {
+			return opts, err
+		}
+
+		opts.Queries = append(opts.Queries, expected)
+	}
+
+	if opts.ReadyTimeout, err = cmd.Flags().GetDuration("ready-timeout"); err != nil {
+		return opts, err
+	}
+
+	if opts.CountTolerance, err = cmd.Flags().GetInt64("count-tolerance"); err != nil {
+		return opts, err
+	}
+
+	if opts.CountTolerance < 0 {
+		return opts, fmt.Errorf("invalid count tolerance %d", opts.CountTolerance)
+	}
+
+	return opts, nil
+}
+
+// parseQueryExpectation parses <query>=<count>, the query may contain '='.
+func parseQueryExpectation(s string) (solrbackup.QueryExpectation, error) {
+	i := strings.LastIndex(s, "=")
+	if i <= 0 {
+		return solrbackup.QueryExpectation{}, fmt.Errorf("invalid verify query %q, expected <query>=<count>", s)
+	}
+
+	count, err := strconv.ParseInt(strings.TrimSpace(s[i+1:]), 10, 64)
+	if err != nil {
+		return solrbackup.QueryExpectation{}, fmt.Errorf("invalid count of verify query %q: %v", s, err)
+	}
+
+	return solrbackup.QueryExpectation{Query: s[:i], Count: count}, nil
+}
+
+func init() {
+	verifyCmd.Flags().StringArray("verify-query", []string{}, "query and the document count it should match, as <query>=<count>")
+	verifyCmd.Flags().Duration("ready-timeout", 0, "max wait for replicas to become active, 0 checks them once")
+	verifyCmd.Flags().Int64("count-tolerance", 0, "max difference between document count and count recorded at backup")
+
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -4,6 +4,7 @@ go 1.16
 
 require (
 	github.com/jedib0t/go-pretty/v6 v6.3.1
+	github.com/mitchellh/mapstructure v1.4.3
 	github.com/onsi/ginkgo v1.16.5
 	github.com/onsi/gomega v1.18.1
 	github.com/spf13/cobra v1.4.0
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	klog "k8s.io/klog/v2"
+)
+
+// ResolveAliases resolves configured names which are aliases. A single collection
+// alias is backed up under the alias name, so the backup name stays stable while the
+// alias is rotated between collections. Collections of a multi collection alias are
+// backed up under their own names.
+func ResolveAliases(ctx context.Context, client *Client, config Config) (Config, error) {
+	aliases, err := client.ListAliases(ctx)
+
+	if err != nil {
+		return config, err
+	}
+
+	targets := make(map[string]string)
+	cols := make([]string, 0, len(config.Collections))
+	seen := make(map[string]bool)
+
+	add := func(col string) {
+		if !seen[col] {
+			seen[col] = true
+			cols = append(cols, col)
+		}
+	}
+
+	for _, name := range config.Collections {
+		aliased, ok := aliases[name]
+
+		switch {
+		case !ok:
+			add(name)
+		case len(aliased) == 1:
+			klog.V(1).Infof("alias %s points to collection %s", name, aliased[0])
+			targets[name] = aliased[0]
+			add(name)
+		default:
+			klog.Warningf("alias %s points to multiple collections %v, they are processed under their own names", name, aliased)
+			for _, col := range aliased {
+				add(col)
+			}
+		}
+	}
+
+	config.Collections = cols
+	config.AliasTargets = targets
+
+	return config, nil
+}
+
+// aliasOf returns the alias which the named backup was taken for, and the collection
+// the alias pointed to. Current alias targets are preferred, the journal is used for
+// aliases which no longer exist.
+func aliasOf(client *Client, config Config, name string) (alias, collection string) {
+	if target, ok := config.AliasTargets[name]; ok {
+		return name, target
+	}
+
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		klog.Warningf("cannot read journal to resolve alias of %s: %v", name, err)
+
+		return "", name
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+
+		if entry.Operation == "backup" && entry.Outcome == journalCompleted && backupBaseName(entry.Name) == name && entry.Alias != "" {
+			return entry.Alias, entry.Collection
+		}
+	}
+
+	return "", name
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Alias Tests", func() {
+	Context("Resolve Aliases Tests", func() {
+
+		var dir string
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"aliases":{"products":"products_20221001","logs":"logs_2021,logs_2022"},"properties":{}}`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		Describe("Test resolve", func() {
+			It("Should map single collection aliases and expand multi collection aliases", func() {
+				var config Config
+				config.SolrEndpoint = server.URL
+				config.Collections = []string{"products", "logs", "orders"}
+
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				config, err = ResolveAliases(context.Background(), client, config)
+				Expect(err).To(BeNil(), "ResolveAliases returns error")
+				Expect(config.Collections).To(Equal([]string{"products", "logs_2021", "logs_2022", "orders"}))
+				Expect(config.AliasTargets).To(Equal(map[string]string{"products": "products_20221001"}))
+				Expect(config.collectionName(0)).To(Equal("products_20221001"))
+				Expect(config.collectionName(3)).To(Equal("orders"))
+			})
+		})
+
+		Describe("Test alias of removed alias", func() {
+			It("Should be read from journal", func() {
+				config := Config{SolrEndpoint: server.URL, JournalFile: filepath.Join(dir, "journal.jsonl")}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				journal := client.Journal()
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", Operation: "backup", Collection: "catalog_1", Name: "catalog", Alias: "catalog", State: journalSubmitted})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", State: journalCompleted})).To(BeNil())
+
+				alias, collection := aliasOf(client, config, "catalog")
+				Expect(alias).To(Equal("catalog"))
+				Expect(collection).To(Equal("catalog_1"))
+
+				alias, collection = aliasOf(client, config, "orders")
+				Expect(alias).To(BeEmpty())
+				Expect(collection).To(Equal("orders"))
+			})
+		})
+
+	})
+})
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Authenticator adds credentials to solr requests.
+type Authenticator interface {
+	Authenticate(req *http.Request) error
+}
+
+// BasicAuth authenticates with solr BasicAuthPlugin credentials.
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+
+	return nil
+}
+
+// BearerToken authenticates with a static bearer token, e.g. a JWT for JWTAuthPlugin.
+type BearerToken struct {
+	Token string
+}
+
+func (a BearerToken) Authenticate(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+
+	return nil
+}
+
+// BearerTokenFile authenticates with a bearer token read from a file. The file is
+// read on each request, so rotated kubernetes secrets are picked up without restart.
+type BearerTokenFile struct {
+	Path string
+}
+
+func (a BearerTokenFile) Authenticate(req *http.Request) error {
+	token, err := ioutil.ReadFile(a.Path)
+
+	if err != nil {
+		return fmt.Errorf("cannot read token file: %v", err)
+	}
+
+	return BearerToken{Token: strings.TrimSpace(string(token))}.Authenticate(req)
+}
+
+// newAuthenticator returns the authenticator configured by config, or nil when
+// requests should be sent unauthenticated.
+func newAuthenticator(config Config) (Authenticator, error) {
+	basic := config.Username != "" || config.Password != ""
+	bearer := config.Token != "" || config.TokenFile != ""
+
+	switch {
+	case basic && bearer:
+		return nil, errors.New("basic auth and bearer token cannot be used together")
+	case config.Token != "" && config.TokenFile != "":
+		return nil, errors.New("token and token file cannot be used together")
+	case basic:
+		if config.Username == "" {
+			return nil, errors.New("username is required for basic auth")
+		}
+		return BasicAuth{Username: config.Username, Password: config.Password}, nil
+	case config.Token != "":
+		return BearerToken{Token: config.Token}, nil
+	case config.TokenFile != "":
+		return BearerTokenFile{Path: config.TokenFile}, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Authentication Tests", func() {
+	Context("Authenticator Tests", func() {
+
+		newRequest := func() *http.Request {
+			req, err := http.NewRequest(http.MethodGet, "http://localhost:8983/solr/admin/collections", nil)
+			Expect(err).To(BeNil(), "new request returns error")
+			return req
+		}
+
+		Describe("Test basic auth", func() {
+			It("Should set credentials", func() {
+				auth, err := newAuthenticator(Config{Username: "solr", Password: "SolrRocks"})
+				Expect(err).To(BeNil(), "newAuthenticator returns error")
+
+				req := newRequest()
+				Expect(auth.Authenticate(req)).To(BeNil())
+
+				username, password, ok := req.BasicAuth()
+				Expect(ok).To(BeTrue())
+				Expect(username).To(Equal("solr"))
+				Expect(password).To(Equal("SolrRocks"))
+			})
+
+			It("Should not be combined with token", func() {
+				_, err := newAuthenticator(Config{Username: "solr", Token: "jwt"})
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+		Describe("Test token file", func() {
+			It("Should re-read token on each request", func() {
+				dir, err := ioutil.TempDir("", "solr-backup")
+				Expect(err).To(BeNil(), "cannot create temp dir")
+				defer os.RemoveAll(dir)
+
+				path := filepath.Join(dir, "token")
+				Expect(ioutil.WriteFile(path, []byte("first\n"), 0600)).To(BeNil())
+
+				auth, err := newAuthenticator(Config{TokenFile: path})
+				Expect(err).To(BeNil(), "newAuthenticator returns error")
+
+				req := newRequest()
+				Expect(auth.Authenticate(req)).To(BeNil())
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer first"))
+
+				Expect(ioutil.WriteFile(path, []byte("second"), 0600)).To(BeNil())
+
+				req = newRequest()
+				Expect(auth.Authenticate(req)).To(BeNil())
+				Expect(req.Header.Get("Authorization")).To(Equal("Bearer second"))
+			})
+		})
+
+		Describe("Test no auth", func() {
+			It("Should return nil authenticator", func() {
+				auth, err := newAuthenticator(Config{})
+				Expect(err).To(BeNil())
+				Expect(auth).To(BeNil())
+			})
+		})
+
+	})
+})
@@ -14,140 +14,131 @@ limitations under the License.
 package solrbackup
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	prettytable "github.com/jedib0t/go-pretty/v6/table"
 	klog "k8s.io/klog/v2"
 	"os"
-	"reflect"
 	"sort"
 	"time"
 )
 
-func startDelete(config Config, colId, backupId, reqId int64) error {
+func startDelete(ctx context.Context, client *Client, config Config, colId, backupId int64, reqId string) error {
 	col := config.Collections[colId]
 
-	var delete_uri string
+	params := DeleteBackupParams{Name: col, Location: config.location(colId), Repository: config.repository(colId), BackupId: backupId, PurgeUnused: backupId == -1}
 
-	if backupId == -1 {
-		delete_uri = fmt.Sprintf("%s%s?action=DELETEBACKUP&async=sb-%d&name=%s&location=%s&purgeUnused=true", config.SolrEndpoint, collection_api, reqId, col, config.Location)
-	} else {
-		delete_uri = fmt.Sprintf("%s%s?action=DELETEBACKUP&async=sb-%d&name=%s&location=%s&backupId=%d", config.SolrEndpoint, collection_api, reqId, col, config.Location, backupId)
-	}
-
-	klog.V(5).Infof("delete uri: %v", delete_uri)
-
-	resp, err := sendRequest(delete_uri)
-
-	if err != nil {
+	if err := client.DeleteBackup(ctx, reqId, params); err != nil {
 		klog.Errorf("error: %v", err)
 
 		return err
 	}
 
-	v, ok := resp["error"]
-	if ok && v == "Task with the same requestid already exists." {
-		klog.Error("error: %v", v)
+	return nil
+}
 
-		return err
+// deletedBackups returns the backup points a completed DELETEBACKUP request deleted.
+func deletedBackups(resp *RequestStatusResponse) ([]DeletedBackup, error) {
+	var result DeleteBackupResponse
+
+	if err := json.Unmarshal(resp.Raw, &result); err != nil {
+		return nil, fmt.Errorf("cannot decode deleted backups: %v", err)
 	}
 
-	return nil
+	return result.Deleted, nil
 }
 
-func backupPurgeUnused(config Config, colId int64) error {
-	reqId := time.Now().UnixMilli()
+// logDeletedBackups logs the backup points a completed DELETEBACKUP request deleted.
+func logDeletedBackups(col string, resp *RequestStatusResponse) {
+	deleted, err := deletedBackups(resp)
 
-	if err := startDelete(config, colId, -1, reqId); err != nil {
-		return err
-	}
+	if err != nil {
+		klog.Warningf("%s: %v", col, err)
 
-	if err := waitRequestStatus(config, reqId); err != nil {
-		return err
+		return
 	}
 
-	if err := deleteRequestId(config, reqId); err != nil {
-		return err
+	for _, backup := range deleted {
+		klog.V(1).Infof("deleted backup %d of %s started at %s, %d files, %d bytes", backup.BackupId, col, backup.StartTime, backup.NumFiles, backup.Size)
 	}
-
-	return nil
 }
 
-func BackupDeleteWithColIdWithBackupId(config Config, colId, backupId int64) error {
-	reqId := time.Now().UnixMilli()
+func backupPurgeUnused(ctx context.Context, client *Client, config Config, colId int64) error {
+	reqId := client.newRequestId()
 
-	if err := startDelete(config, colId, backupId, reqId); err != nil {
+	if err := startDelete(ctx, client, config, colId, -1, reqId); err != nil {
 		return err
 	}
 
-	if err := waitRequestStatus(config, reqId); err != nil {
+	resp, err := waitRequestResult(ctx, client, reqId, config.waitOptions(config.DeleteTimeout))
+
+	if err != nil {
 		return err
 	}
 
-	if err := deleteRequestId(config, reqId); err != nil {
+	logDeletedBackups(config.Collections[colId], resp)
+
+	if err := deleteRequestId(ctx, client, reqId); err != nil {
 		return err
 	}
 
-	return backupPurgeUnused(config, colId)
+	return nil
 }
 
-func BackupDelete(config Config, colId int64) error {
-	before := time.Now().AddDate(0, 0, -1*config.RetaintionDays)
+func BackupDeleteWithColIdWithBackupId(ctx context.Context, client *Client, config Config, colId, backupId int64) error {
+	if config.backupMode(colId) == BackupModeFull {
+		return deleteFullBackup(ctx, client, config, colId, backupId)
+	}
 
-	backups, err := backupListRetrive(config, colId)
+	reqId := client.newRequestId()
 
-	if err != nil {
+	if err := startDelete(ctx, client, config, colId, backupId, reqId); err != nil {
 		return err
 	}
 
-	backupIds := make([]int, 0)
-
-	for i := 0; i < backups.Len(); i++ {
-		backup := backups.Index(i).Interface().(map[string]interface{})
-
-		backupId := int(backup["backupId"].(float64))
-		startTimeStr := backup["startTime"].(string)
+	resp, err := waitRequestResult(ctx, client, reqId, config.waitOptions(config.DeleteTimeout))
 
-		date, err := time.Parse("2006-01-02T15:04:05.000000Z", startTimeStr)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			klog.Errorf("cannot parse start time %v", err)
+	logDeletedBackups(config.Collections[colId], resp)
 
-			return err
-		}
+	if err := deleteRequestId(ctx, client, reqId); err != nil {
+		return err
+	}
 
-		if date.Before(before) {
-			backupIds = append(backupIds, backupId)
-		}
+	return backupPurgeUnused(ctx, client, config, colId)
+}
 
-	}
+// BackupDelete deletes backup points of the collection which are not kept by the
+// retention policy.
+func BackupDelete(ctx context.Context, client *Client, config Config, colId int64) error {
+	plan, err := planCollectionPrune(ctx, client, config, colId, time.Now())
 
-	for _, backupId := range backupIds {
-		if err := BackupDeleteWithColIdWithBackupId(config, colId, int64(backupId)); err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return applyCollectionPrune(ctx, client, config, colId, plan)
 }
 
-func BackupDeleteAll(config Config) error {
-	for colId, _ := range config.Collections {
-		if err := BackupDelete(config, int64(colId)); err != nil {
-			return err
-		}
-	}
-
-	return nil
+func BackupDeleteAll(ctx context.Context, client *Client, config Config) error {
+	return forEachCollection(ctx, config, "delete", func(colId int64) error {
+		return BackupDelete(ctx, client, config, colId)
+	})
 }
 
-func backupListRetrive(config Config, colId int64) (*reflect.Value, error) {
+func backupListRetrive(ctx context.Context, client *Client, config Config, colId int64) ([]BackupPoint, error) {
 	col := config.Collections[colId]
 
-	backup_uri := fmt.Sprintf("%s%s?action=LISTBACKUP&name=%s&location=%s", config.SolrEndpoint, collection_api, col, config.Location)
-	klog.V(5).Infof("backup uri: %v", backup_uri)
+	if config.backupMode(colId) == BackupModeFull {
+		return listFullBackups(client, config, colId)
+	}
 
-	resp, err := sendRequest(backup_uri)
+	resp, err := client.ListBackups(ctx, col, config.location(colId), config.repository(colId))
 
 	if err != nil {
 		klog.Errorf("error: %v", err)
@@ -155,45 +146,23 @@ func backupListRetrive(config Config, colId int64) (*reflect.Value, error) {
 		return nil, err
 	}
 
-	v, ok := resp["error"]
-	if ok && v == "Task with the same requestid already exists." {
-		klog.Error("error: %v", v)
-
-		return nil, err
-	}
-
-	tmp_backups, ok := resp["backups"]
-
-	if !ok {
+	if resp.Backups == nil {
 		return nil, errors.New("backups key not found")
 	}
 
-	backups := reflect.ValueOf(tmp_backups)
-
-	return &backups, nil
+	return resp.Backups, nil
 }
 
-func BackupList(config Config, colId int64) error {
-	backups, err := backupListRetrive(config, colId)
+func BackupList(ctx context.Context, client *Client, config Config, colId int64) error {
+	backups, err := backupListRetrive(ctx, client, config, colId)
 
 	if err != nil {
 		return err
 	}
 
-	backupList := make(map[int]map[string]interface{})
-	backupIds := make([]int, 0)
-
-	for i := 0; i < backups.Len(); i++ {
-		backup := backups.Index(i).Interface().(map[string]interface{})
-
-		backupId := int(backup["backupId"].(float64))
-
-		backupList[backupId] = backup
-
-		backupIds = append(backupIds, backupId)
-	}
-
-	sort.Ints(backupIds[:])
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].BackupId < backups[j].BackupId
+	})
 
 	col := config.Collections[colId]
 
@@ -201,10 +170,8 @@ func BackupList(config Config, colId int64) error {
 	t.SetOutputMirror(os.Stdout)
 	t.AppendHeader(prettytable.Row{"#", "Collection", "Config Name", "Alias", "Backup Time"})
 
-	for _, i := range backupIds {
-		backup := backupList[i]
-
-		t.AppendRow(prettytable.Row{backup["backupId"], col, backup["collection.configName"], backup["collectionAlias"], backup["startTime"]})
+	for _, backup := range backups {
+		t.AppendRow(prettytable.Row{backup.BackupId, col, backup.ConfigName, backup.CollectionAlias, backup.StartTime})
 
 	}
 
@@ -213,9 +180,9 @@ func BackupList(config Config, colId int64) error {
 	return nil
 }
 
-func BackupListAll(config Config) error {
+func BackupListAll(ctx context.Context, client *Client, config Config) error {
 	for colId, _ := range config.Collections {
-		if err := BackupList(config, int64(colId)); err != nil {
+		if err := BackupList(ctx, client, config, int64(colId)); err != nil {
 			return err
 		}
 	}
@@ -223,55 +190,117 @@ func BackupListAll(config Config) error {
 	return nil
 }
 
-func StartBackup(config Config, colId, reqId int64) error {
+func StartBackup(ctx context.Context, client *Client, config Config, colId int64, reqId string) error {
 
 	col := config.Collections[colId]
+	params := BackupParams{Collection: config.collectionName(colId), Name: col, Location: config.location(colId), Repository: config.repository(colId)}
+
+	if params.Collection != col {
+		params.Alias = col
+	}
 
-	backup_uri := fmt.Sprintf("%s%s?action=BACKUP&async=sb-%d&collection=%s&name=%s&location=%s&incremental=true", config.SolrEndpoint, collection_api, reqId, col, col, config.Location)
-	klog.V(5).Infof("backup uri: %v", backup_uri)
+	switch mode := config.backupMode(colId); mode {
+	case BackupModeIncremental:
+	case BackupModeFull:
+		params.Name = fullBackupName(col, fullBackupId(time.Now()))
+		params.Full = true
 
-	resp, err := sendRequest(backup_uri)
+		if client.Journal() == nil {
+			klog.Warningf("full backup %s is taken without journal, it can only be restored by its backup id", params.Name)
+		}
+	default:
+		return fmt.Errorf("unknown backup mode %q of %s", mode, col)
+	}
 
-	if err != nil {
+	if err := client.Backup(ctx, reqId, params); err != nil {
 		klog.Errorf("error: %v", err)
 
 		return err
 	}
 
-	v, ok := resp["error"]
-	if ok && v == "Task with the same requestid already exists." {
-		klog.Error("error: %v", v)
+	return nil
+}
+
+// recordBackupPoint records the document count taken before the backup and the
+// backup point it created, so restores can be verified against them.
+func recordBackupPoint(ctx context.Context, client *Client, config Config, colId int64, reqId string, documents *int64) {
+	entry := JournalEntry{RequestId: reqId, State: journalCompleted, Documents: documents}
 
-		return err
+	backups, err := backupListRetrive(ctx, client, config, colId)
+
+	if err != nil {
+		klog.Warningf("cannot find backup point of %s: %v", config.Collections[colId], err)
 	}
 
-	return nil
+	for i, backup := range backups {
+		if entry.BackupId == nil || backup.BackupId > *entry.BackupId {
+			entry.BackupId = &backups[i].BackupId
+		}
+	}
+
+	client.journal.record(entry)
 }
 
-func Backup(config Config, colId int64) error {
-	reqId := time.Now().UnixMilli()
+// settledDocuments counts the documents of the collection again after its backup
+// completed. Solr takes the snapshot sometime between submitting BACKUP and its
+// completion, so the count taken before submitting only matches the backup point
+// when nothing was indexed meanwhile. When the counts differ the backup point
+// gets no document count and restores of it skip the comparison.
+func settledDocuments(ctx context.Context, client *Client, config Config, colId int64, before *int64) *int64 {
+	if before == nil {
+		return nil
+	}
+
+	after, err := client.Count(ctx, config.collectionName(colId), "*:*")
 
-	if err := StartBackup(config, colId, reqId); err != nil {
-		return err
+	if err != nil {
+		klog.Warningf("cannot count documents of %s, restores cannot be verified against this backup: %v", config.Collections[colId], err)
+		return nil
+	}
+
+	if after != *before {
+		klog.Warningf("documents of %s changed from %d to %d during backup, restores cannot be verified against its document count", config.Collections[colId], *before, after)
+		return nil
+	}
+
+	return before
+}
+
+func Backup(ctx context.Context, client *Client, config Config, colId int64) error {
+	reqId := client.newRequestId()
+
+	var documents *int64
+
+	if client.journal != nil {
+		if count, err := client.Count(ctx, config.collectionName(colId), "*:*"); err != nil {
+			klog.Warningf("cannot count documents of %s, restores cannot be verified against this backup: %v", config.Collections[colId], err)
+		} else {
+			documents = &count
+		}
 	}
 
-	if err := waitRequestStatus(config, reqId); err != nil {
+	if err := StartBackup(ctx, client, config, colId, reqId); err != nil {
 		return err
 	}
 
-	if err := deleteRequestId(config, reqId); err != nil {
+	if err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.BackupTimeout)); err != nil {
 		return err
 	}
 
-	return nil
-}
+	if client.journal != nil {
+		documents = settledDocuments(ctx, client, config, colId, documents)
+		recordBackupPoint(ctx, client, config, colId, reqId, documents)
+	}
 
-func BackupAll(config Config) error {
-	for colId, _ := range config.Collections {
-		if err := Backup(config, int64(colId)); err != nil {
-			return err
-		}
+	if err := deleteRequestId(ctx, client, reqId); err != nil {
+		return err
 	}
 
 	return nil
 }
+
+func BackupAll(ctx context.Context, client *Client, config Config) error {
+	return forEachCollection(ctx, config, "backup", func(colId int64) error {
+		return Backup(ctx, client, config, colId)
+	})
+}
@@ -14,9 +14,9 @@ limitations under the License.
 package solrbackup
 
 import (
+	"context"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"time"
 )
 
 var _ = Describe("Backup Methods Tests", func() {
@@ -28,7 +28,10 @@ var _ = Describe("Backup Methods Tests", func() {
 		config.Location = "/"
 		config.RetaintionDays = 1
 
-		reqId := time.Now().UnixMilli()
+		ctx := context.Background()
+		client, _ := NewClient(config)
+
+		reqId := client.newRequestId()
 
 		Describe("Test open/close", func() {
 			It("Should be succeed", func() {})
@@ -36,55 +39,55 @@ var _ = Describe("Backup Methods Tests", func() {
 
 		Describe("Test backup single manually", func() {
 			It("StartBackup should be succeed", func() {
-				err := StartBackup(config, 0, reqId)
+				err := StartBackup(ctx, client, config, 0, reqId)
 				Expect(err).To(BeNil(), "start backup returns error")
 			})
 
 			It("waitRequestStatus should be succeed", func() {
-				err := waitRequestStatus(config, reqId)
+				err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.BackupTimeout))
 				Expect(err).To(BeNil(), "waitRequestStatus returns error")
 			})
 
 			It("deleteRequestId should be succeed", func() {
-				err := deleteRequestId(config, reqId)
+				err := deleteRequestId(ctx, client, reqId)
 				Expect(err).To(BeNil(), "deleteRequestId returns error")
 			})
 		})
 
 		Describe("Test backup single together", func() {
 			It("Backup should be succeed", func() {
-				err := Backup(config, 0)
+				err := Backup(ctx, client, config, 0)
 				Expect(err).To(BeNil(), "Backup returns error")
 			})
 		})
 
 		Describe("Test backup all together", func() {
 			It("BackupAll should be succeed", func() {
-				err := BackupAll(config)
+				err := BackupAll(ctx, client, config)
 				Expect(err).To(BeNil(), "BackupAll returns error")
 			})
 		})
 
 		Describe("Test list backup", func() {
 			It("BackupList should be succeed", func() {
-				err := BackupList(config, 0)
+				err := BackupList(ctx, client, config, 0)
 				Expect(err).To(BeNil(), "BackupList returns error")
 			})
 
 			It("BackupListAll should be succeed", func() {
-				err := BackupListAll(config)
+				err := BackupListAll(ctx, client, config)
 				Expect(err).To(BeNil(), "BackupListAll returns error")
 			})
 		})
 
 		Describe("Test delete backup", func() {
 			It("BackupDelete should be succeed", func() {
-				err := BackupDelete(config, 0)
+				err := BackupDelete(ctx, client, config, 0)
 				Expect(err).To(BeNil(), "BackupDelete returns error")
 			})
 
 			It("BackupDeleteAll should be succeed", func() {
-				err := BackupDeleteAll(config)
+				err := BackupDeleteAll(ctx, client, config)
 				Expect(err).To(BeNil(), "BackupDeleteAll returns error")
 			})
 		})
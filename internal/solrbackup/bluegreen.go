@@ -0,0 +1,181 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"time"
+)
+
+// BlueGreenOptions controls restoring a backup next to the live collection and
+// switching an alias to it.
+type BlueGreenOptions struct {
+	// RestoreOptions selects the backup point and settings of the new collection,
+	// its target collection is generated.
+	RestoreOptions
+	// Alias is switched to the restored collection. It defaults to the alias the
+	// backup was taken for, or the backup name.
+	Alias string
+	// ValidationQuery must match at least MinDocuments documents of the restored
+	// collection before the alias is switched.
+	ValidationQuery string
+	MinDocuments    int64
+	// ReadyTimeout is the max wait for all replicas of the restored collection to
+	// become active.
+	ReadyTimeout time.Duration
+	// DeletePrevious deletes collections the alias pointed to after GracePeriod.
+	DeletePrevious bool
+	GracePeriod    time.Duration
+}
+
+func deleteCollection(ctx context.Context, client *Client, config Config, collection string) error {
+	reqId := client.newRequestId()
+
+	if err := client.DeleteCollection(ctx, reqId, collection); err != nil {
+		return err
+	}
+
+	if err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.DeleteTimeout)); err != nil {
+		return err
+	}
+
+	return deleteRequestId(ctx, client, reqId)
+}
+
+// blueGreenAlias returns the alias to switch and the collections it points to now.
+func blueGreenAlias(ctx context.Context, client *Client, config Config, colId int64, opts BlueGreenOptions) (string, []string, error) {
+	alias := opts.Alias
+	if alias == "" {
+		if alias, _ = aliasOf(client, config, config.Collections[colId]); alias == "" {
+			alias = config.Collections[colId]
+		}
+	}
+
+	aliases, err := client.ListAliases(ctx)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if previous, ok := aliases[alias]; ok {
+		return alias, previous, nil
+	}
+
+	cols, err := client.ListCollections(ctx)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, col := range cols {
+		if col == alias {
+			return "", nil, fmt.Errorf("%s is a collection, blue/green restore needs an alias in front of it", alias)
+		}
+	}
+
+	return alias, nil, nil
+}
+
+// RestoreBlueGreen restores the backup into a new <name>_restore_<timestamp>
+// collection, waits until all its replicas are active, validates it with a query and
+// atomically switches the alias to it. Live data is never overwritten; the previous
+// collections are optionally deleted after a grace period.
+func RestoreBlueGreen(ctx context.Context, client *Client, config Config, colId int64, opts BlueGreenOptions) error {
+	col := config.Collections[colId]
+
+	alias, previous, err := blueGreenAlias(ctx, client, config, colId, opts)
+
+	if err != nil {
+		return err
+	}
+
+	restoreOpts := opts.RestoreOptions
+	restoreOpts.TargetCollection = fmt.Sprintf("%s_restore_%s", col, time.Now().UTC().Format("20060102150405"))
+	target := restoreOpts.TargetCollection
+
+	klog.V(1).Infof("restoring %s into %s for alias %s", col, target, alias)
+
+	backupId, err := restore(ctx, client, config, colId, restoreOpts)
+
+	if err != nil {
+		return err
+	}
+
+	if err := waitCollectionActive(ctx, client, target, config.waitOptions(opts.ReadyTimeout)); err != nil {
+		return fmt.Errorf("restored collection %s is kept for inspection: %w", target, err)
+	}
+
+	query := opts.ValidationQuery
+	if query == "" {
+		query = "*:*"
+	}
+
+	count, err := client.Count(ctx, target, query)
+
+	if err != nil {
+		return fmt.Errorf("restored collection %s is kept for inspection: %w", target, err)
+	}
+
+	if count < opts.MinDocuments {
+		return fmt.Errorf("validation of %s failed: query %q matched %d documents, expected at least %d", target, query, count, opts.MinDocuments)
+	}
+
+	klog.V(1).Infof("validation query %q matched %d documents of %s", query, count, target)
+
+	if opts.Verify != nil {
+		if err := Verify(ctx, client, config, colId, target, backupId, *opts.Verify); err != nil {
+			return fmt.Errorf("restored collection %s is kept for inspection: %w", target, err)
+		}
+	}
+
+	if err := client.CreateAlias(ctx, alias, []string{target}); err != nil {
+		return err
+	}
+
+	klog.V(0).Infof("alias %s points to %s (previously %v)", alias, target, previous)
+
+	if !opts.DeletePrevious || len(previous) == 0 {
+		return nil
+	}
+
+	klog.V(1).Infof("deleting previous collections %v after %v", previous, opts.GracePeriod)
+
+	if err := sleepContext(ctx, opts.GracePeriod); err != nil {
+		return err
+	}
+
+	for _, prev := range previous {
+		if prev == target {
+			continue
+		}
+
+		if err := deleteCollection(ctx, client, config, prev); err != nil {
+			return fmt.Errorf("cannot delete previous collection %s: %w", prev, err)
+		}
+	}
+
+	return nil
+}
+
+func RestoreAllBlueGreen(ctx context.Context, client *Client, config Config, opts BlueGreenOptions) error {
+	if opts.Alias != "" && len(config.Collections) != 1 {
+		return fmt.Errorf("alias can only be given for a single collection, got %d", len(config.Collections))
+	}
+
+	return forEachCollection(ctx, config, "blue/green restore", func(colId int64) error {
+		return RestoreBlueGreen(ctx, client, config, colId, opts)
+	})
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+var _ = Describe("Blue Green Restore Tests", func() {
+	Context("Alias Switch Tests", func() {
+
+		var server *httptest.Server
+		var mu sync.Mutex
+		var restoredInto, aliasTarget string
+		var deletedCollections []string
+		var numFound int
+
+		BeforeEach(func() {
+			restoredInto, aliasTarget, deletedCollections, numFound = "", "", nil, 10
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				q := r.URL.Query()
+
+				if strings.HasSuffix(r.URL.Path, "/select") {
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"response":{"numFound":%d,"start":0,"docs":[]}}`, numFound)
+					return
+				}
+
+				switch q.Get("action") {
+				case "LISTALIASES":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"aliases":{"products":"products_v1"}}`)
+				case "RESTORE":
+					restoredInto = q.Get("collection")
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "DELETE":
+					deletedCollections = append(deletedCollections, q.Get("name"))
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "REQUESTSTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":""}}`)
+				case "DELETESTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "CLUSTERSTATUS":
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"cluster":{"collections":{"%s":{"shards":{"shard1":{"state":"active","replicas":{"core_node1":{"node_name":"n1:8983_solr","state":"active","leader":"true"}}}}}},"live_nodes":["n1:8983_solr"]}}`, q.Get("collection"))
+				case "CREATEALIAS":
+					aliasTarget = q.Get("collections")
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		newConfig := func() (Config, *Client) {
+			config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"products"}}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			config, err = ResolveAliases(context.Background(), client, config)
+			Expect(err).To(BeNil(), "ResolveAliases returns error")
+			return config, client
+		}
+
+		Describe("Test successful restore", func() {
+			It("Should switch alias and delete previous collection", func() {
+				config, client := newConfig()
+
+				err := RestoreBlueGreen(context.Background(), client, config, 0, BlueGreenOptions{MinDocuments: 1, DeletePrevious: true})
+				Expect(err).To(BeNil(), "RestoreBlueGreen returns error")
+				Expect(restoredInto).To(HavePrefix("products_restore_"))
+				Expect(aliasTarget).To(Equal(restoredInto))
+				Expect(deletedCollections).To(Equal([]string{"products_v1"}))
+			})
+		})
+
+		Describe("Test failed validation", func() {
+			It("Should not switch alias", func() {
+				config, client := newConfig()
+				numFound = 0
+
+				err := RestoreBlueGreen(context.Background(), client, config, 0, BlueGreenOptions{MinDocuments: 1, DeletePrevious: true})
+				Expect(err).NotTo(BeNil())
+				Expect(aliasTarget).To(BeEmpty())
+				Expect(deletedCollections).To(BeEmpty())
+			})
+		})
+
+	})
+})
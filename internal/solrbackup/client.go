@@ -0,0 +1,616 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	klog "k8s.io/klog/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRequestTimeout = time.Minute
+)
+
+// Client is a solr collections api client. It is safe for concurrent use.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	auth       Authenticator
+	retry      RetryPolicy
+	location   string
+	scope      string
+	journal    *Journal
+}
+
+type BackupParams struct {
+	Collection string
+	Name       string
+	Location   string
+	Repository string
+	// Full takes a self-contained full snapshot instead of an incremental backup point.
+	Full bool
+	// Alias is only recorded to the journal as the alias Collection is backed up for.
+	Alias string
+	// Safety marks a backup taken before a restore overwrites Collection, it is
+	// journaled as a safetybackup operation instead of a backup.
+	Safety bool
+}
+
+// CreateOptions are settings of a collection created by restore. Zero values are
+// left to solr defaults.
+type CreateOptions struct {
+	ConfigName        string
+	ReplicationFactor int
+	NrtReplicas       int
+	TlogReplicas      int
+	PullReplicas      int
+	MaxShardsPerNode  int
+	CreateNodeSet     []string
+}
+
+// merge returns o with its zero fields taken from defaults.
+func (o CreateOptions) merge(defaults CreateOptions) CreateOptions {
+	if o.ConfigName == "" {
+		o.ConfigName = defaults.ConfigName
+	}
+	if o.ReplicationFactor == 0 {
+		o.ReplicationFactor = defaults.ReplicationFactor
+	}
+	if o.NrtReplicas == 0 {
+		o.NrtReplicas = defaults.NrtReplicas
+	}
+	if o.TlogReplicas == 0 {
+		o.TlogReplicas = defaults.TlogReplicas
+	}
+	if o.PullReplicas == 0 {
+		o.PullReplicas = defaults.PullReplicas
+	}
+	if o.MaxShardsPerNode == 0 {
+		o.MaxShardsPerNode = defaults.MaxShardsPerNode
+	}
+	if len(o.CreateNodeSet) == 0 {
+		o.CreateNodeSet = defaults.CreateNodeSet
+	}
+
+	return o
+}
+
+type RestoreParams struct {
+	Collection string
+	Name       string
+	Location   string
+	Repository string
+	// BackupId is the backup point to restore, latest one is restored when nil.
+	BackupId *int64
+	CreateOptions
+}
+
+type DeleteBackupParams struct {
+	Name        string
+	Location    string
+	Repository  string
+	BackupId    int64
+	PurgeUnused bool
+}
+
+// NewClient creates a client for config's solr endpoint. Config's location is used
+// for operations which do not specify one.
+func NewClient(config Config) (*Client, error) {
+	timeout := config.RequestTimeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+
+	auth, err := newAuthenticator(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	retry := config.Retry
+	if retry.MaxAttempts <= 0 {
+		retry = DefaultRetryPolicy()
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Client{
+		endpoint:   strings.TrimSuffix(config.SolrEndpoint, "/"),
+		httpClient: &http.Client{Timeout: timeout, Transport: transport},
+		auth:       auth,
+		retry:      retry,
+		location:   config.Location,
+		scope:      config.RequestIdScope,
+		journal:    OpenJournal(config.JournalFile),
+	}, nil
+}
+
+// Journal returns the journal of async requests submitted by the client, it is nil
+// when journaling is disabled.
+func (c *Client) Journal() *Journal {
+	return c.journal
+}
+
+func (c *Client) newRequestId() string {
+	return newRequestId(c.scope)
+}
+
+func (c *Client) locationOrDefault(location string) string {
+	if location == "" {
+		return c.location
+	}
+
+	return location
+}
+
+// setRepository selects the backup repository, solr uses its default repository
+// when it is not set.
+func setRepository(params url.Values, repository string) {
+	if repository != "" {
+		params.Set("repository", repository)
+	}
+}
+
+// do sends an idempotent collections api request, retrying it on transient failures.
+func (c *Client) do(ctx context.Context, params url.Values, out interface{}) error {
+	return c.doPath(ctx, collection_api, params.Get("action"), params, out)
+}
+
+// doPath sends an idempotent request to the given solr path, retrying it on transient
+// failures. Action names the request in logs and errors.
+func (c *Client) doPath(ctx context.Context, path, action string, params url.Values, out interface{}) error {
+	return c.retry.retry(ctx, action, func() error {
+		return c.doOnce(ctx, path, action, params, out)
+	})
+}
+
+func (c *Client) doOnce(ctx context.Context, path, action string, params url.Values, out interface{}) error {
+	uri := fmt.Sprintf("%s%s?%s", c.endpoint, path, params.Encode())
+	klog.V(5).Infof("%s uri: %v", strings.ToLower(action), uri)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if c.auth != nil {
+		if err := c.auth.Authenticate(req); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+
+	if err != nil {
+		klog.Errorf("error while get reqeust: %v", err)
+
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		klog.Errorf("error while reading response: %v", err)
+
+		return err
+	}
+
+	klog.V(5).Infof("body: %v", string(body))
+
+	var envelope Response
+
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return checkResponse(action, resp.StatusCode, Response{}, body)
+		}
+
+		klog.Errorf("error while reading response: %v", err)
+
+		return err
+	}
+
+	if err := checkResponse(action, resp.StatusCode, envelope, body); err != nil {
+		klog.Errorf("error: %v", err)
+
+		return err
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		klog.Errorf("error while reading response: %v", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// submit sends an async collections api request with the given request id and
+// records it to the journal. Request details are filled into entry from params,
+// the operation defaults to the action.
+func (c *Client) submit(ctx context.Context, requestId string, params url.Values, entry JournalEntry) error {
+	params.Set("async", requestId)
+
+	entry.RequestId = requestId
+	if entry.Operation == "" {
+		entry.Operation = strings.ToLower(params.Get("action"))
+	}
+	entry.Collection = params.Get("collection")
+	entry.Name = params.Get("name")
+	entry.State = journalSubmitted
+
+	if entry.Collection == "" {
+		entry.Collection = entry.Name
+	}
+	c.journal.record(entry)
+
+	err := c.submitWithRetry(ctx, requestId, params)
+	if err != nil {
+		entry.State = journalRejected
+		entry.Message = err.Error()
+		c.journal.record(entry)
+	}
+
+	return err
+}
+
+// submitWithRetry only retries a failed submission when REQUESTSTATUS confirms solr
+// did not register the request id, so the operation never runs twice.
+func (c *Client) submitWithRetry(ctx context.Context, requestId string, params url.Values) error {
+	action := params.Get("action")
+	policy := c.retry
+
+	for attempt := 1; ; attempt++ {
+		var resp AsyncResponse
+
+		err := c.doOnce(ctx, collection_api, action, params, &resp)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			return err
+		}
+
+		status, statusErr := c.RequestStatus(ctx, requestId)
+		if statusErr != nil {
+			klog.Errorf("cannot confirm %s submission of %s: %v", action, requestId, statusErr)
+
+			return err
+		}
+
+		if status.Status.State != "notfound" {
+			klog.Warningf("%s submission of %s failed but request is registered at solr: %v", action, requestId, err)
+
+			return nil
+		}
+
+		d := policy.backoff(attempt)
+		klog.Warningf("%s failed, retrying in %v (attempt %d/%d): %v", action, d, attempt, policy.MaxAttempts, err)
+
+		if err := sleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// Backup submits an async BACKUP request, an incremental one unless p.Full is set.
+func (c *Client) Backup(ctx context.Context, requestId string, p BackupParams) error {
+	params := url.Values{}
+	params.Set("action", "BACKUP")
+	params.Set("collection", p.Collection)
+	params.Set("name", p.Name)
+	params.Set("location", c.locationOrDefault(p.Location))
+	setRepository(params, p.Repository)
+	params.Set("incremental", strconv.FormatBool(!p.Full))
+
+	entry := JournalEntry{Alias: p.Alias}
+	if p.Safety {
+		entry.Operation = safetyBackupOperation
+	}
+
+	return c.submit(ctx, requestId, params, entry)
+}
+
+// Restore submits an async RESTORE request.
+func (c *Client) Restore(ctx context.Context, requestId string, p RestoreParams) error {
+	params := url.Values{}
+	params.Set("action", "RESTORE")
+	params.Set("collection", p.Collection)
+	params.Set("name", p.Name)
+	params.Set("location", c.locationOrDefault(p.Location))
+	setRepository(params, p.Repository)
+
+	if p.BackupId != nil {
+		params.Set("backupId", strconv.FormatInt(*p.BackupId, 10))
+	}
+
+	if p.ConfigName != "" {
+		params.Set("collection.configName", p.ConfigName)
+	}
+
+	setPositive := func(key string, value int) {
+		if value > 0 {
+			params.Set(key, strconv.Itoa(value))
+		}
+	}
+	setPositive("replicationFactor", p.ReplicationFactor)
+	setPositive("nrtReplicas", p.NrtReplicas)
+	setPositive("tlogReplicas", p.TlogReplicas)
+	setPositive("pullReplicas", p.PullReplicas)
+	setPositive("maxShardsPerNode", p.MaxShardsPerNode)
+
+	if len(p.CreateNodeSet) > 0 {
+		params.Set("createNodeSet", strings.Join(p.CreateNodeSet, ","))
+	}
+
+	return c.submit(ctx, requestId, params, JournalEntry{})
+}
+
+// DeleteBackup submits an async DELETEBACKUP request. It deletes the given backup point
+// or, when PurgeUnused is set, the files no longer referenced by any backup point.
+func (c *Client) DeleteBackup(ctx context.Context, requestId string, p DeleteBackupParams) error {
+	params := url.Values{}
+	params.Set("action", "DELETEBACKUP")
+	params.Set("name", p.Name)
+	params.Set("location", c.locationOrDefault(p.Location))
+	setRepository(params, p.Repository)
+
+	if p.PurgeUnused {
+		params.Set("purgeUnused", "true")
+	} else {
+		params.Set("backupId", strconv.FormatInt(p.BackupId, 10))
+	}
+
+	return c.submit(ctx, requestId, params, JournalEntry{})
+}
+
+// ListBackups returns backup points of the named backup.
+func (c *Client) ListBackups(ctx context.Context, name, location, repository string) (*ListBackupsResponse, error) {
+	params := url.Values{}
+	params.Set("action", "LISTBACKUP")
+	params.Set("name", name)
+	params.Set("location", c.locationOrDefault(location))
+	setRepository(params, repository)
+
+	var resp ListBackupsResponse
+
+	if err := c.do(ctx, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// ProbeRepository lists backups of a name which is not expected to exist through
+// the repository, solr fails with an unknown repository error before looking
+// for the backups when the repository is not configured.
+func (c *Client) ProbeRepository(ctx context.Context, repository string) error {
+	params := url.Values{}
+	params.Set("action", "LISTBACKUP")
+	params.Set("name", repositoryProbeName)
+	setRepository(params, repository)
+
+	var resp ListBackupsResponse
+
+	return c.do(ctx, params, &resp)
+}
+
+// ListAliases returns collections of each alias in the cluster.
+func (c *Client) ListAliases(ctx context.Context) (map[string][]string, error) {
+	params := url.Values{}
+	params.Set("action", "LISTALIASES")
+
+	var resp ListAliasesResponse
+
+	if err := c.do(ctx, params, &resp); err != nil {
+		return nil, err
+	}
+
+	aliases := make(map[string][]string, len(resp.Aliases))
+	for alias, cols := range resp.Aliases {
+		aliases[alias] = strings.Split(cols, ",")
+	}
+
+	return aliases, nil
+}
+
+// CreateAlias creates the alias or points an existing one to the given collections.
+func (c *Client) CreateAlias(ctx context.Context, name string, collections []string) error {
+	params := url.Values{}
+	params.Set("action", "CREATEALIAS")
+	params.Set("name", name)
+	params.Set("collections", strings.Join(collections, ","))
+
+	var resp Response
+
+	return c.do(ctx, params, &resp)
+}
+
+// ClusterStatus returns status of the collection and live nodes of the cluster.
+func (c *Client) ClusterStatus(ctx context.Context, collection string) (*ClusterStatusResponse, error) {
+	params := url.Values{}
+	params.Set("action", "CLUSTERSTATUS")
+	params.Set("collection", collection)
+
+	var resp ClusterStatusResponse
+
+	if err := c.do(ctx, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
+
+// DeleteCollection submits an async DELETE request of the collection.
+func (c *Client) DeleteCollection(ctx context.Context, requestId, collection string) error {
+	params := url.Values{}
+	params.Set("action", "DELETE")
+	params.Set("name", collection)
+
+	return c.submit(ctx, requestId, params, JournalEntry{})
+}
+
+// Count returns number of documents of the collection matching the query.
+func (c *Client) Count(ctx context.Context, collection, query string) (int64, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("rows", "0")
+	params.Set("wt", "json")
+
+	var resp QueryResponse
+
+	if err := c.doPath(ctx, "/solr/"+url.PathEscape(collection)+"/select", "QUERY", params, &resp); err != nil {
+		return 0, err
+	}
+
+	return resp.Result.NumFound, nil
+}
+
+// SolrXML returns solr.xml stored in zookeeper.
+func (c *Client) SolrXML(ctx context.Context) ([]byte, error) {
+	params := url.Values{}
+	params.Set("detail", "true")
+	params.Set("path", "/solr.xml")
+	params.Set("wt", "json")
+
+	var resp ZookeeperResponse
+
+	if err := c.doPath(ctx, "/solr/admin/zookeeper", "ZOOKEEPER", params, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Znode.Data == "" {
+		return nil, errors.New("solr.xml is not stored in zookeeper, repositories are configured in solr.xml of each node")
+	}
+
+	return []byte(resp.Znode.Data), nil
+}
+
+// ListCollections returns names of the collections in the cluster.
+func (c *Client) ListCollections(ctx context.Context) ([]string, error) {
+	params := url.Values{}
+	params.Set("action", "LIST")
+
+	var resp ListCollectionsResponse
+
+	if err := c.do(ctx, params, &resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Collections, nil
+}
+
+// RequestStatus returns the status of the async request.
+func (c *Client) RequestStatus(ctx context.Context, requestId string) (*RequestStatusResponse, error) {
+	params := url.Values{}
+	params.Set("action", "REQUESTSTATUS")
+	params.Set("requestid", requestId)
+
+	var raw json.RawMessage
+
+	if err := c.do(ctx, params, &raw); err != nil {
+		return nil, err
+	}
+
+	var resp RequestStatusResponse
+
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("cannot decode status of request %s: %v", requestId, err)
+	}
+
+	resp.Raw = raw
+
+	if resp.Status.State == "" {
+		return nil, fmt.Errorf("status of request %s not found", requestId)
+	}
+
+	klog.V(5).Infof("request status response %+v", resp.Status)
+
+	return &resp, nil
+}
+
+// ListRequestIds returns async request ids of this tool known to solr. Solr versions
+// which require requestid for REQUESTSTATUS reject this with a SolrError.
+func (c *Client) ListRequestIds(ctx context.Context) ([]string, error) {
+	params := url.Values{}
+	params.Set("action", "REQUESTSTATUS")
+
+	var raw interface{}
+
+	if err := c.do(ctx, params, &raw); err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, e := range v {
+				if strings.HasPrefix(k, requestIdPrefix) && !seen[k] {
+					seen[k] = true
+					ids = append(ids, k)
+				}
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range v {
+				walk(e)
+			}
+		case string:
+			if strings.HasPrefix(v, requestIdPrefix) && !seen[v] {
+				seen[v] = true
+				ids = append(ids, v)
+			}
+		}
+	}
+	walk(raw)
+
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+// DeleteStatus deletes the stored status of the async request.
+func (c *Client) DeleteStatus(ctx context.Context, requestId string) error {
+	params := url.Values{}
+	params.Set("action", "DELETESTATUS")
+	params.Set("requestid", requestId)
+
+	var resp Response
+
+	if err := c.do(ctx, params, &resp); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// collectionProblems returns why the collection is not fully serving: active shards
+// without an active leader and replicas which are not active on a live node.
+func collectionProblems(status *ClusterStatusResponse, collection string) []string {
+	col, ok := status.Cluster.Collections[collection]
+	if !ok {
+		return []string{fmt.Sprintf("collection %s not found", collection)}
+	}
+
+	live := make(map[string]bool)
+	for _, node := range status.Cluster.LiveNodes {
+		live[node] = true
+	}
+
+	problems := make([]string, 0)
+
+	shards := make([]string, 0, len(col.Shards))
+	for shard := range col.Shards {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+
+	for _, name := range shards {
+		shard := col.Shards[name]
+
+		// inactive shards are parents of completed splits
+		if shard.State != "" && shard.State != "active" {
+			continue
+		}
+
+		hasLeader := false
+
+		replicas := make([]string, 0, len(shard.Replicas))
+		for replica := range shard.Replicas {
+			replicas = append(replicas, replica)
+		}
+		sort.Strings(replicas)
+
+		for _, replicaName := range replicas {
+			replica := shard.Replicas[replicaName]
+			active := replica.State == "active" && live[replica.NodeName]
+
+			if !active {
+				problems = append(problems, fmt.Sprintf("%s/%s is %s on %s", name, replicaName, replica.State, replica.NodeName))
+			}
+
+			if active && replica.Leader == "true" {
+				hasLeader = true
+			}
+		}
+
+		if !hasLeader {
+			problems = append(problems, fmt.Sprintf("%s has no active leader", name))
+		}
+	}
+
+	return problems
+}
+
+// checkCollectionHealth returns an error describing all problems of the collection.
+func checkCollectionHealth(ctx context.Context, client *Client, collection string) error {
+	status, err := client.ClusterStatus(ctx, collection)
+
+	if err != nil {
+		return err
+	}
+
+	if problems := collectionProblems(status, collection); len(problems) > 0 {
+		return fmt.Errorf("collection %s is not healthy: %s", collection, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// waitCollectionActive polls cluster status until every shard of the collection has
+// an active leader and all replicas are active, or the timeout passes.
+func waitCollectionActive(ctx context.Context, client *Client, collection string, opts WaitOptions) error {
+	start := time.Now()
+
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+
+	for {
+		err := checkCollectionHealth(ctx, client, collection)
+
+		if err == nil {
+			klog.V(1).Infof("collection %s is active after %v", collection, time.Since(start).Round(time.Second))
+
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return fmt.Errorf("collection %s did not become active in %v: %v", collection, time.Since(start).Round(time.Second), err)
+		}
+
+		var solrErr *SolrError
+		if errors.As(err, &solrErr) {
+			return err
+		}
+
+		klog.V(5).Infof("waiting for collection %s: %v", collection, err)
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return fmt.Errorf("collection %s did not become active in %v: %v", collection, time.Since(start).Round(time.Second), err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cluster Status Tests", func() {
+	Context("Collection Problems Tests", func() {
+
+		decode := func(body string) *ClusterStatusResponse {
+			var resp ClusterStatusResponse
+			Expect(json.Unmarshal([]byte(body), &resp)).To(BeNil(), "unmarshal returns error")
+			return &resp
+		}
+
+		Describe("Test healthy collection", func() {
+			It("Should have no problems", func() {
+				status := decode(`{"responseHeader":{"status":0,"QTime":1},"cluster":{"collections":{"test":{"shards":{
+					"shard1":{"state":"active","replicas":{"core_node1":{"node_name":"n1:8983_solr","state":"active","leader":"true"},"core_node2":{"node_name":"n2:8983_solr","state":"active"}}},
+					"shard2":{"state":"inactive","replicas":{"core_node3":{"node_name":"n1:8983_solr","state":"down"}}}}}},
+					"live_nodes":["n1:8983_solr","n2:8983_solr"]}}`)
+				Expect(collectionProblems(status, "test")).To(BeEmpty())
+			})
+		})
+
+		Describe("Test unhealthy collection", func() {
+			It("Should report replicas and leaders", func() {
+				status := decode(`{"responseHeader":{"status":0,"QTime":1},"cluster":{"collections":{"test":{"shards":{
+					"shard1":{"state":"active","replicas":{"core_node1":{"node_name":"n1:8983_solr","state":"recovering","leader":"true"},"core_node2":{"node_name":"n3:8983_solr","state":"active"}}}}}},
+					"live_nodes":["n1:8983_solr"]}}`)
+				Expect(collectionProblems(status, "test")).To(Equal([]string{
+					"shard1/core_node1 is recovering on n1:8983_solr",
+					"shard1/core_node2 is active on n3:8983_solr",
+					"shard1 has no active leader",
+				}))
+			})
+
+			It("Missing collection should be reported", func() {
+				status := decode(`{"responseHeader":{"status":0,"QTime":1},"cluster":{"collections":{},"live_nodes":[]}}`)
+				Expect(collectionProblems(status, "test")).To(HaveLen(1))
+			})
+		})
+
+	})
+})
@@ -13,9 +13,200 @@ limitations under the License.
 
 package solrbackup
 
+import (
+	"strings"
+	"time"
+)
+
 type Config struct {
-	SolrEndpoint   string
-	Location       string
-	Collections    []string
+	SolrEndpoint string
+	Location     string
+	Collections  []string
+	// Include and Exclude select cluster collections by glob or "re:" prefixed
+	// regular expression patterns, see ResolveCollections.
+	Include []string
+	Exclude []string
+	// AliasTargets maps configured aliases to their collections, it is set by
+	// ResolveAliases.
+	AliasTargets map[string]string
+	// RestoreAliases points aliases to their restored collections after restore.
+	RestoreAliases bool
 	RetaintionDays int
+	// Repository is the backup repository of solr.xml used by all operations, solr
+	// uses its default repository when it is empty.
+	Repository string
+	// BackupMode is BackupModeIncremental (default) or BackupModeFull.
+	BackupMode string
+	// Retention selects backup points kept by prune, RetaintionDays is used as its
+	// Days when they are not set.
+	Retention      RetentionPolicy
+	RequestTimeout time.Duration
+	Username       string
+	Password       string
+	Token          string
+	TokenFile      string
+
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+
+	Retry RetryPolicy
+
+	BackupTimeout   time.Duration
+	RestoreTimeout  time.Duration
+	DeleteTimeout   time.Duration
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	PollBackoff     float64
+
+	// Concurrency is the number of collections processed in parallel.
+	Concurrency int
+
+	// Defaults override the settings above for all collections, and Overrides for
+	// the collections they are keyed by.
+	Defaults  CollectionConfig
+	Overrides map[string]CollectionConfig
+	// Tags selects collections having any of the schedule tags, all when empty.
+	Tags []string
+
+	// RequestIdScope is put into async request ids, it defaults to host name.
+	RequestIdScope string
+	JournalFile    string
+}
+
+// CollectionConfig holds settings of a collection which override the global ones,
+// empty fields keep them.
+type CollectionConfig struct {
+	Location   string
+	Repository string
+	BackupMode string
+	Retention  *RetentionPolicy
+	// Tags are schedule tags of the collection, see Config.Tags.
+	Tags []string
+	// Group is the concurrency group of the collection, collections of the same
+	// group are never processed in parallel.
+	Group string
+	// Restore holds defaults of the restore options of the collection.
+	Restore CreateOptions
+}
+
+// merge returns o with its empty fields taken from defaults.
+func (o CollectionConfig) merge(defaults CollectionConfig) CollectionConfig {
+	if o.Location == "" {
+		o.Location = defaults.Location
+	}
+	if o.Repository == "" {
+		o.Repository = defaults.Repository
+	}
+	if o.BackupMode == "" {
+		o.BackupMode = defaults.BackupMode
+	}
+	if o.Retention == nil {
+		o.Retention = defaults.Retention
+	}
+	if len(o.Tags) == 0 {
+		o.Tags = defaults.Tags
+	}
+	if o.Group == "" {
+		o.Group = defaults.Group
+	}
+	o.Restore = o.Restore.merge(defaults.Restore)
+
+	return o
+}
+
+// settings returns the overridden settings of the collection.
+func (c Config) settings(colId int64) CollectionConfig {
+	return c.Overrides[c.Collections[colId]].merge(c.Defaults)
+}
+
+// location returns the backup location of the collection.
+func (c Config) location(colId int64) string {
+	if location := c.settings(colId).Location; location != "" {
+		return location
+	}
+
+	return c.Location
+}
+
+// repository returns the backup repository of the collection.
+func (c Config) repository(colId int64) string {
+	if repository := c.settings(colId).Repository; repository != "" {
+		return repository
+	}
+
+	return c.Repository
+}
+
+// backupMode returns the backup mode of the collection.
+func (c Config) backupMode(colId int64) string {
+	if mode := c.settings(colId).BackupMode; mode != "" {
+		return mode
+	}
+
+	if c.BackupMode != "" {
+		return c.BackupMode
+	}
+
+	return BackupModeIncremental
+}
+
+// retentionPolicy returns the retention policy of the collection.
+func (c Config) retentionPolicy(colId int64) RetentionPolicy {
+	if policy := c.settings(colId).Retention; policy != nil {
+		return *policy
+	}
+
+	policy := c.Retention
+	if policy.Days == 0 {
+		policy.Days = c.RetaintionDays
+	}
+
+	return policy
+}
+
+// WaitOptions controls polling of an async request until it finishes.
+type WaitOptions struct {
+	// Timeout is the overall deadline of the wait, zero means no deadline.
+	Timeout         time.Duration
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// PollBackoff multiplies poll interval after each poll, values below 1 keep it constant.
+	PollBackoff float64
+}
+
+// collectionName returns the collection which is backed up under the configured name.
+func (c Config) collectionName(colId int64) string {
+	name := c.Collections[colId]
+
+	if target, ok := c.AliasTargets[name]; ok {
+		return target
+	}
+
+	return name
+}
+
+// operationTimeout returns wait timeout of the given collections api action.
+func (c Config) operationTimeout(action string) time.Duration {
+	switch strings.ToUpper(action) {
+	case "BACKUP", "SAFETYBACKUP":
+		return c.BackupTimeout
+	case "RESTORE":
+		return c.RestoreTimeout
+	case "DELETEBACKUP", "DELETE":
+		return c.DeleteTimeout
+	}
+
+	return 0
+}
+
+func (c Config) waitOptions(timeout time.Duration) WaitOptions {
+	return WaitOptions{
+		Timeout:         timeout,
+		PollInterval:    c.PollInterval,
+		MaxPollInterval: c.MaxPollInterval,
+		PollBackoff:     c.PollBackoff,
+	}
 }
@@ -0,0 +1,137 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Config Tests", func() {
+	Context("Collection Override Tests", func() {
+
+		newConfig := func() Config {
+			return Config{
+				Location:       "/backups",
+				RetaintionDays: 7,
+				Collections:    []string{"products", "logs", "events", "orders"},
+				Defaults:       CollectionConfig{Tags: []string{"nightly"}, Restore: CreateOptions{ReplicationFactor: 3}},
+				Overrides: map[string]CollectionConfig{
+					"logs":   {Location: "/big", Repository: "s3", Retention: &RetentionPolicy{KeepLast: 3}, Tags: []string{"weekly"}, Group: "big", Restore: CreateOptions{ConfigName: "logs"}},
+					"events": {Group: "big"},
+				},
+			}
+		}
+
+		Describe("Test settings", func() {
+			It("Should take overrides, then defaults, then global settings", func() {
+				config := newConfig()
+
+				Expect(config.location(0)).To(Equal("/backups"))
+				Expect(config.location(1)).To(Equal("/big"))
+				Expect(config.repository(0)).To(BeEmpty())
+				Expect(config.repository(1)).To(Equal("s3"))
+				Expect(config.retentionPolicy(0)).To(Equal(RetentionPolicy{Days: 7}))
+				Expect(config.retentionPolicy(1)).To(Equal(RetentionPolicy{KeepLast: 3}))
+				Expect(config.settings(0).Tags).To(Equal([]string{"nightly"}))
+				Expect(config.settings(1).Restore).To(Equal(CreateOptions{ConfigName: "logs", ReplicationFactor: 3}))
+			})
+		})
+
+		Describe("Test schedule tags", func() {
+			It("Should select tagged collections", func() {
+				config := newConfig()
+				config.Tags = []string{"weekly"}
+
+				Expect(selectTagged(config).Collections).To(Equal([]string{"logs"}))
+			})
+
+			It("Should select all collections without tags", func() {
+				Expect(selectTagged(newConfig()).Collections).To(HaveLen(4))
+			})
+		})
+
+		Describe("Test concurrency groups", func() {
+			It("Should batch collections of the same group", func() {
+				Expect(collectionBatches(newConfig())).To(Equal([][]int64{{0}, {1, 2}, {3}}))
+			})
+
+			It("Should not run collections of the same group in parallel", func() {
+				config := newConfig()
+				config.Concurrency = 4
+
+				var mu sync.Mutex
+				running := make(map[string]bool)
+				overlapped := false
+
+				err := forEachCollection(context.Background(), config, "test", func(colId int64) error {
+					group := config.settings(colId).Group
+
+					mu.Lock()
+					if group != "" && running[group] {
+						overlapped = true
+					}
+					running[group] = true
+					mu.Unlock()
+
+					sleepContext(context.Background(), 10*time.Millisecond)
+
+					mu.Lock()
+					running[group] = false
+					mu.Unlock()
+
+					return nil
+				})
+				Expect(err).To(BeNil())
+				Expect(overlapped).To(BeFalse())
+			})
+		})
+
+		Describe("Test overridden location and repository", func() {
+			It("Should be sent to solr", func() {
+				var mu sync.Mutex
+				var requests []string
+
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					mu.Lock()
+					defer mu.Unlock()
+
+					q := r.URL.Query()
+					requests = append(requests, fmt.Sprintf("%s %s %s %s", q.Get("action"), q.Get("name"), q.Get("location"), q.Get("repository")))
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collection":"logs","backups":[]}`)
+				}))
+				defer server.Close()
+
+				config := newConfig()
+				config.SolrEndpoint = server.URL
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				_, err = backupListRetrive(context.Background(), client, config, 0)
+				Expect(err).To(BeNil())
+				_, err = backupListRetrive(context.Background(), client, config, 1)
+				Expect(err).To(BeNil())
+
+				Expect(requests).To(Equal([]string{"LISTBACKUP products /backups ", "LISTBACKUP logs /big s3"}))
+			})
+		})
+
+	})
+})
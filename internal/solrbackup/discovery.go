@@ -0,0 +1,237 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	regexPatternPrefix = "re:"
+)
+
+// matchPattern matches name against a glob pattern, or against a regular expression
+// when the pattern is prefixed with "re:". Regular expressions match whole names.
+func matchPattern(pattern, name string) (bool, error) {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(pattern, regexPatternPrefix) + ")$")
+
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+
+		return re.MatchString(name), nil
+	}
+
+	ok, err := path.Match(pattern, name)
+
+	if err != nil {
+		return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+
+	return ok, nil
+}
+
+func matchAny(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		if ok, err := matchPattern(pattern, name); err != nil || ok {
+			return ok, err
+		}
+	}
+
+	return false, nil
+}
+
+// selectCollections returns configured collections and the cluster collections
+// matching include patterns (all when there is none) but not exclude patterns.
+func selectCollections(config Config, clusterCollections []string) ([]string, error) {
+	selected := make(map[string]bool)
+
+	for _, col := range config.Collections {
+		selected[col] = true
+	}
+
+	for _, col := range clusterCollections {
+		if len(config.Include) > 0 {
+			if ok, err := matchAny(config.Include, col); err != nil {
+				return nil, err
+			} else if !ok {
+				continue
+			}
+		}
+
+		if ok, err := matchAny(config.Exclude, col); err != nil {
+			return nil, err
+		} else if ok {
+			klog.V(5).Infof("collection %s is excluded", col)
+			continue
+		}
+
+		selected[col] = true
+	}
+
+	cols := make([]string, 0, len(selected))
+	for col := range selected {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	return cols, nil
+}
+
+// selectTagged returns config with only the collections having any of its schedule
+// tags, all collections when it has none.
+func selectTagged(config Config) Config {
+	if len(config.Tags) == 0 {
+		return config
+	}
+
+	wanted := make(map[string]bool)
+	for _, tag := range config.Tags {
+		wanted[tag] = true
+	}
+
+	cols := make([]string, 0, len(config.Collections))
+
+	for colId, col := range config.Collections {
+		for _, tag := range config.settings(int64(colId)).Tags {
+			if wanted[tag] {
+				cols = append(cols, col)
+				break
+			}
+		}
+	}
+
+	klog.V(1).Infof("collections tagged %v: %v", config.Tags, cols)
+
+	config.Collections = cols
+
+	return config
+}
+
+// ResolveCollections discovers cluster collections when include or exclude patterns
+// are configured and returns config with the selected collections having any of its
+// schedule tags. It warns about collections which were backed up before but are no
+// longer selected, and fails when a selected collection has no backup location.
+func ResolveCollections(ctx context.Context, client *Client, config Config) (Config, error) {
+	if len(config.Include) == 0 && len(config.Exclude) == 0 {
+		return requireLocations(selectTagged(config))
+	}
+
+	clusterCollections, err := client.ListCollections(ctx)
+
+	if err != nil {
+		return config, err
+	}
+
+	cols, err := selectCollections(config, clusterCollections)
+
+	if err != nil {
+		return config, err
+	}
+
+	klog.V(1).Infof("resolved collections: %v", cols)
+
+	warnMissingCollections(client, cols, clusterCollections)
+
+	config.Collections = cols
+
+	return requireLocations(selectTagged(config))
+}
+
+// requireLocations checks that every collection has a backup location, given
+// globally, by the defaults or by the collection itself.
+func requireLocations(config Config) (Config, error) {
+	missing := make([]string, 0)
+
+	for colId, col := range config.Collections {
+		if config.location(int64(colId)) == "" {
+			missing = append(missing, col)
+		}
+	}
+
+	if len(missing) > 0 {
+		return config, fmt.Errorf("backup location is required for %s", strings.Join(missing, ", "))
+	}
+
+	return config, nil
+}
+
+func warnMissingCollections(client *Client, selected, clusterCollections []string) {
+	if client.Journal() == nil {
+		klog.Warning("journal is disabled, backed up collections which are no longer selected cannot be detected")
+
+		return
+	}
+
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		klog.Warningf("cannot read journal to check missing collections: %v", err)
+
+		return
+	}
+
+	for _, warning := range missingBackups(entries, selected, clusterCollections) {
+		klog.Warning(warning)
+	}
+}
+
+// missingBackups returns warnings about backup names which were backed up before
+// but are not selected now. Backups are matched by name, which is the alias for
+// collections backed up through an alias.
+func missingBackups(entries []JournalEntry, selected, clusterCollections []string) []string {
+	exists := make(map[string]bool)
+	for _, col := range clusterCollections {
+		exists[col] = true
+	}
+
+	isSelected := make(map[string]bool)
+	for _, col := range selected {
+		isSelected[col] = true
+	}
+
+	warned := make(map[string]bool)
+	warnings := make([]string, 0)
+
+	for _, entry := range entries {
+		name := backupBaseName(entry.Name)
+		if name == "" {
+			name = entry.Collection
+		}
+
+		if entry.Operation != "backup" || entry.Outcome != journalCompleted || isSelected[name] || warned[name] {
+			continue
+		}
+
+		warned[name] = true
+
+		switch {
+		case entry.Alias != "":
+			warnings = append(warnings, fmt.Sprintf("alias %s was backed up before but is no longer selected", name))
+		case exists[name]:
+			warnings = append(warnings, fmt.Sprintf("collection %s was backed up before but is no longer selected", name))
+		default:
+			warnings = append(warnings, fmt.Sprintf("collection %s was backed up before but no longer exists", name))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Collection Discovery Tests", func() {
+	Context("Select Collections Tests", func() {
+
+		clusterCollections := []string{".system", "products", "orders", "tmp_reindex", "tmp_test", "logs_2022"}
+
+		Describe("Test exclude patterns", func() {
+			It("Should select all but excluded collections", func() {
+				var config Config
+				config.Exclude = []string{"tmp_*", ".system"}
+
+				cols, err := selectCollections(config, clusterCollections)
+				Expect(err).To(BeNil(), "selectCollections returns error")
+				Expect(cols).To(Equal([]string{"logs_2022", "orders", "products"}))
+			})
+		})
+
+		Describe("Test include patterns", func() {
+			It("Should select matching collections", func() {
+				var config Config
+				config.Collections = []string{"manual"}
+				config.Include = []string{"re:logs_\\d+", "orders"}
+
+				cols, err := selectCollections(config, clusterCollections)
+				Expect(err).To(BeNil(), "selectCollections returns error")
+				Expect(cols).To(Equal([]string{"logs_2022", "manual", "orders"}))
+			})
+
+			It("Regex should match whole name", func() {
+				ok, err := matchPattern("re:tmp", "tmp_test")
+				Expect(err).To(BeNil())
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+		Describe("Test backup locations", func() {
+			It("Should accept locations of defaults and collections", func() {
+				config := Config{Collections: []string{"products", "logs"}, Defaults: CollectionConfig{Location: "/backups"}}
+
+				_, err := ResolveCollections(context.Background(), nil, config)
+				Expect(err).To(BeNil())
+
+				config.Defaults = CollectionConfig{}
+				config.Overrides = map[string]CollectionConfig{"products": {Location: "/backups/products"}, "logs": {Location: "/backups/logs"}}
+
+				_, err = ResolveCollections(context.Background(), nil, config)
+				Expect(err).To(BeNil())
+			})
+
+			It("Should refuse collections without location", func() {
+				config := Config{Collections: []string{"products", "logs"}, Overrides: map[string]CollectionConfig{"logs": {Location: "/backups/logs"}}}
+
+				_, err := ResolveCollections(context.Background(), nil, config)
+				Expect(err).To(MatchError("backup location is required for products"))
+			})
+		})
+
+		Describe("Test invalid patterns", func() {
+			It("Should return error", func() {
+				var config Config
+				config.Include = []string{"re:("}
+
+				_, err := selectCollections(config, clusterCollections)
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+	})
+
+	Context("Missing Backups Tests", func() {
+
+		Describe("Test backups taken through aliases", func() {
+			It("Should be matched by backup name", func() {
+				entries := []JournalEntry{
+					{Operation: "backup", Collection: "products_v2", Name: "products", Alias: "products", Outcome: journalCompleted},
+					{Operation: "backup", Collection: "logs_v1", Name: "logs", Alias: "logs", Outcome: journalCompleted},
+					{Operation: "backup", Collection: "orders", Name: "orders_full_20221001010203", Outcome: journalCompleted},
+					{Operation: "backup", Collection: "tmp", Name: "tmp", Outcome: journalCompleted},
+				}
+
+				warnings := missingBackups(entries, []string{"products", "orders"}, []string{"products_v2", "orders"})
+				Expect(warnings).To(Equal([]string{
+					"alias logs was backed up before but is no longer selected",
+					"collection tmp was backed up before but no longer exists",
+				}))
+			})
+		})
+
+	})
+})
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SolrError is an error reported by solr, either with a failure http status or
+// inside the response payload.
+type SolrError struct {
+	Action     string
+	HTTPStatus int
+	Code       int
+	Message    string
+	Trace      string
+	Metadata   []string
+}
+
+func (e *SolrError) Error() string {
+	return fmt.Sprintf("solr %s failed: code=%d msg=%s", e.Action, e.Code, e.Message)
+}
+
+// ErrorClass returns the solr exception class from error metadata, if any.
+func (e *SolrError) ErrorClass() string {
+	for i := 0; i+1 < len(e.Metadata); i += 2 {
+		if e.Metadata[i] == "error-class" {
+			return e.Metadata[i+1]
+		}
+	}
+
+	return ""
+}
+
+// checkResponse inspects http status and response envelope of an action and
+// returns a SolrError if any of them reports a failure. Exceptions of
+// REQUESTSTATUS belong to the queried task, so they are left to its caller.
+func checkResponse(action string, httpStatus int, resp Response, body []byte) error {
+	solrErr := &SolrError{Action: action, HTTPStatus: httpStatus, Code: httpStatus}
+
+	if resp.ResponseHeader.Status != 0 {
+		solrErr.Code = resp.ResponseHeader.Status
+	}
+
+	switch {
+	case resp.Error != nil:
+		if resp.Error.Code != 0 {
+			solrErr.Code = resp.Error.Code
+		}
+		solrErr.Message = resp.Error.Msg
+		solrErr.Trace = resp.Error.Trace
+		solrErr.Metadata = resp.Error.Metadata
+	case resp.Exception != nil && action != "REQUESTSTATUS":
+		if resp.Exception.RspCode != 0 {
+			solrErr.Code = resp.Exception.RspCode
+		}
+		solrErr.Message = resp.Exception.Msg
+	case resp.ResponseHeader.Status != 0:
+		solrErr.Message = "response status is not zero"
+	case httpStatus < 200 || httpStatus > 299:
+		solrErr.Message = http.StatusText(httpStatus)
+		if text := strings.TrimSpace(string(body)); text != "" && len(text) < 512 {
+			solrErr.Message = text
+		}
+	default:
+		return nil
+	}
+
+	return solrErr
+}
+
+// WaitTimeoutError is returned when an async request does not finish in time.
+type WaitTimeoutError struct {
+	RequestId string
+	LastState string
+	Elapsed   time.Duration
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("request %s did not finish in %v, last state: %s", e.RequestId, e.Elapsed.Round(time.Second), e.LastState)
+}
+
+// TaskFailedError is returned when an async request fails or is lost at solr. It
+// carries the failure details solr reports through REQUESTSTATUS.
+type TaskFailedError struct {
+	RequestId string
+	State     string
+	Message   string
+	// Exception is the overseer exception of the operation.
+	Exception string
+	// Failures maps failing nodes (or shards) to their error messages.
+	Failures map[string]string
+	Raw      json.RawMessage
+}
+
+func (e *TaskFailedError) Error() string {
+	msg := fmt.Sprintf("request %s %s: %s", e.RequestId, e.State, e.Message)
+
+	if e.Exception != "" {
+		msg += "; exception: " + e.Exception
+	}
+
+	for _, node := range e.Nodes() {
+		msg += fmt.Sprintf("; %s: %s", node, e.Failures[node])
+	}
+
+	return msg
+}
+
+// Nodes returns the failing nodes (or shards) in order.
+func (e *TaskFailedError) Nodes() []string {
+	nodes := make([]string, 0, len(e.Failures))
+
+	for node := range e.Failures {
+		nodes = append(nodes, node)
+	}
+
+	sort.Strings(nodes)
+
+	return nodes
+}
+
+func newTaskFailedError(requestId string, resp *RequestStatusResponse) *TaskFailedError {
+	taskErr := &TaskFailedError{
+		RequestId: requestId,
+		State:     resp.Status.State,
+		Message:   resp.Status.Msg,
+		Failures:  make(map[string]string),
+		Raw:       resp.Raw,
+	}
+
+	if resp.Exception != nil {
+		taskErr.Exception = resp.Exception.Msg
+	}
+
+	// overseer reports the cause as "Operation <action> caused exception:" key
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(resp.Raw, &fields); err == nil {
+		for k, v := range fields {
+			if taskErr.Exception == "" && strings.HasPrefix(k, "Operation ") && strings.HasSuffix(k, "caused exception:") {
+				taskErr.Exception = rawString(v)
+			}
+		}
+	}
+
+	for node, v := range resp.Failure {
+		taskErr.Failures[node] = rawString(v)
+	}
+
+	return taskErr
+}
+
+// rawString returns a json string value as is and any other value as compact json.
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	return string(raw)
+}
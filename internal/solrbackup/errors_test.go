@@ -0,0 +1,119 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"encoding/json"
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Solr Error Tests", func() {
+	Context("Check Response Tests", func() {
+
+		decode := func(body string) Response {
+			var resp Response
+			Expect(json.Unmarshal([]byte(body), &resp)).To(BeNil(), "unmarshal returns error")
+			return resp
+		}
+
+		Describe("Test successful response", func() {
+			It("Should return nil", func() {
+				body := `{"responseHeader":{"status":0,"QTime":1},"requestid":"sb-1"}`
+				Expect(checkResponse("BACKUP", 200, decode(body), []byte(body))).To(BeNil())
+			})
+		})
+
+		Describe("Test error payload", func() {
+			It("Should return SolrError", func() {
+				body := `{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"Task with the same requestid already exists.","code":400}}`
+				err := checkResponse("BACKUP", 400, decode(body), []byte(body))
+
+				var solrErr *SolrError
+				Expect(errors.As(err, &solrErr)).To(BeTrue(), "error is not SolrError")
+				Expect(solrErr.Code).To(Equal(400))
+				Expect(solrErr.Message).To(Equal("Task with the same requestid already exists."))
+				Expect(solrErr.ErrorClass()).To(Equal("org.apache.solr.common.SolrException"))
+			})
+		})
+
+		Describe("Test exception payload", func() {
+			body := `{"responseHeader":{"status":0,"QTime":1},"exception":{"msg":"backup location does not exist","rspCode":400}}`
+
+			It("Should return SolrError", func() {
+				err := checkResponse("BACKUP", 200, decode(body), []byte(body))
+				Expect(err).NotTo(BeNil())
+				Expect(err.(*SolrError).Code).To(Equal(400))
+			})
+
+			It("Should be ignored for REQUESTSTATUS", func() {
+				Expect(checkResponse("REQUESTSTATUS", 200, decode(body), []byte(body))).To(BeNil())
+			})
+		})
+
+		Describe("Test response header status", func() {
+			It("Should return SolrError", func() {
+				body := `{"responseHeader":{"status":500,"QTime":1}}`
+				err := checkResponse("LISTBACKUP", 200, decode(body), []byte(body))
+				Expect(err).NotTo(BeNil())
+				Expect(err.(*SolrError).Code).To(Equal(500))
+			})
+		})
+
+		Describe("Test http status", func() {
+			It("Should return SolrError", func() {
+				err := checkResponse("LISTBACKUP", 503, Response{}, []byte("Service Unavailable"))
+				Expect(err).NotTo(BeNil())
+				Expect(err.(*SolrError).HTTPStatus).To(Equal(503))
+				Expect(err.(*SolrError).Message).To(Equal("Service Unavailable"))
+			})
+		})
+
+	})
+
+	Context("Task Failed Tests", func() {
+
+		Describe("Test failed backup status", func() {
+			It("Should carry solr failure details", func() {
+				raw := []byte(`{"responseHeader":{"status":0,"QTime":1},"Operation backup caused exception:":"org.apache.solr.common.SolrException: Could not backup all shards","exception":{"msg":"Could not backup all shards","rspCode":500},"failure":{"10.0.0.1:8983_solr":"Error from server at http://10.0.0.1:8983/solr: Failed to backup core=test_shard1_replica_n1"},"status":{"state":"failed","msg":"found [sb-1] in failed tasks"}}`)
+
+				var resp RequestStatusResponse
+				Expect(json.Unmarshal(raw, &resp)).To(BeNil(), "unmarshal returns error")
+				resp.Raw = raw
+
+				taskErr := newTaskFailedError("sb-1", &resp)
+				Expect(taskErr.State).To(Equal("failed"))
+				Expect(taskErr.Exception).To(Equal("Could not backup all shards"))
+				Expect(taskErr.Nodes()).To(Equal([]string{"10.0.0.1:8983_solr"}))
+				Expect(taskErr.Error()).To(ContainSubstring("core=test_shard1_replica_n1"))
+				Expect(string(taskErr.Raw)).To(Equal(string(raw)))
+			})
+		})
+
+		Describe("Test not found status", func() {
+			It("Should carry status message", func() {
+				raw := []byte(`{"responseHeader":{"status":0,"QTime":1},"status":{"state":"notfound","msg":"Did not find [sb-1] in any tasks queue"}}`)
+
+				var resp RequestStatusResponse
+				Expect(json.Unmarshal(raw, &resp)).To(BeNil(), "unmarshal returns error")
+				resp.Raw = raw
+
+				taskErr := newTaskFailedError("sb-1", &resp)
+				Expect(taskErr.Error()).To(Equal("request sb-1 notfound: Did not find [sb-1] in any tasks queue"))
+			})
+		})
+
+	})
+})
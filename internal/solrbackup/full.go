@@ -0,0 +1,233 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	klog "k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	BackupModeIncremental = "incremental"
+	BackupModeFull        = "full"
+
+	fullBackupIdLayout = "20060102150405"
+)
+
+// Full backups are self-contained snapshots named <name>_full_<yyyyMMddHHmmss>. Solr
+// cannot list or delete them, so they are listed and deleted from the location
+// mounted to this tool as backup points whose id is their timestamp.
+var fullBackupNamePattern = regexp.MustCompile(`^(.+)_full_(\d{14})$`)
+
+// fullBackupId returns the backup id of a full backup taken at t.
+func fullBackupId(t time.Time) int64 {
+	id, _ := strconv.ParseInt(t.UTC().Format(fullBackupIdLayout), 10, 64)
+
+	return id
+}
+
+// fullBackupName returns the backup name of a full backup of name.
+func fullBackupName(name string, backupId int64) string {
+	return fmt.Sprintf("%s_full_%d", name, backupId)
+}
+
+// backupBaseName returns the configured name a backup name belongs to.
+func backupBaseName(backupName string) string {
+	if m := fullBackupNamePattern.FindStringSubmatch(backupName); m != nil {
+		return m[1]
+	}
+
+	return backupName
+}
+
+// backupDeleted tells whether the journal entry deleted a backup: a completed
+// DELETEBACKUP request or a full backup deleted from the file system.
+func backupDeleted(entry JournalEntry) bool {
+	return entry.Operation == "deletebackup" && (entry.Outcome == journalCompleted || entry.Outcome == "" && entry.State == journalDeleted)
+}
+
+// recordedFullBackups returns completed and not deleted full backups of name in the
+// journal by their backup names.
+func recordedFullBackups(client *Client, name string) (map[string]JournalEntry, error) {
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]bool)
+	for _, entry := range entries {
+		if backupDeleted(entry) {
+			deleted[entry.Name] = true
+		}
+	}
+
+	recorded := make(map[string]JournalEntry)
+
+	for _, entry := range entries {
+		m := fullBackupNamePattern.FindStringSubmatch(entry.Name)
+
+		if entry.Operation == "backup" && entry.Outcome == journalCompleted && m != nil && m[1] == name && !deleted[entry.Name] {
+			recorded[entry.Name] = entry
+		}
+	}
+
+	return recorded, nil
+}
+
+// fullBackupPoint returns the backup point of a full backup name.
+func fullBackupPoint(backupName string) (BackupPoint, error) {
+	m := fullBackupNamePattern.FindStringSubmatch(backupName)
+
+	if m == nil {
+		return BackupPoint{}, fmt.Errorf("invalid full backup name %s", backupName)
+	}
+
+	t, err := time.Parse(fullBackupIdLayout, m[2])
+
+	if err != nil {
+		return BackupPoint{}, fmt.Errorf("invalid full backup name %s: %v", backupName, err)
+	}
+
+	id, _ := strconv.ParseInt(m[2], 10, 64)
+
+	return BackupPoint{BackupId: id, StartTime: t.Format(time.RFC3339Nano)}, nil
+}
+
+// listFullBackups returns full backups of the collection, oldest first. They are
+// listed from the locally mounted backup location and the journal only adds their
+// aliases. Full backups in a backup repository can only be listed from the journal.
+func listFullBackups(client *Client, config Config, colId int64) ([]BackupPoint, error) {
+	name := config.Collections[colId]
+
+	recorded, err := recordedFullBackups(client, name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]BackupPoint, 0)
+
+	if repository := config.repository(colId); repository != "" {
+		if client.Journal() == nil {
+			return nil, fmt.Errorf("full backups of repository %s are listed from the journal, journal is required", repository)
+		}
+
+		for backupName, entry := range recorded {
+			backup, err := fullBackupPoint(backupName)
+
+			if err != nil {
+				return nil, err
+			}
+
+			backup.EndTime = entry.Time.UTC().Format(time.RFC3339Nano)
+			backup.CollectionAlias = entry.Alias
+			backups = append(backups, backup)
+		}
+	} else {
+		location := client.locationOrDefault(config.location(colId))
+
+		infos, err := ioutil.ReadDir(location)
+
+		if err != nil {
+			klog.Errorf("error: %v", err)
+
+			return nil, fmt.Errorf("full backups are listed from the locally mounted location: %v", err)
+		}
+
+		for _, info := range infos {
+			m := fullBackupNamePattern.FindStringSubmatch(info.Name())
+
+			if !info.IsDir() || m == nil || m[1] != name {
+				continue
+			}
+
+			// backup.properties is written when the backup is complete
+			props, err := os.Stat(filepath.Join(location, info.Name(), "backup.properties"))
+
+			if err != nil {
+				klog.V(1).Infof("skipping incomplete full backup %s: %v", info.Name(), err)
+
+				continue
+			}
+
+			backup, err := fullBackupPoint(info.Name())
+
+			if err != nil {
+				return nil, err
+			}
+
+			backup.EndTime = props.ModTime().UTC().Format(time.RFC3339Nano)
+
+			if entry, ok := recorded[info.Name()]; ok {
+				backup.EndTime = entry.Time.UTC().Format(time.RFC3339Nano)
+				backup.CollectionAlias = entry.Alias
+			}
+
+			backups = append(backups, backup)
+		}
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].BackupId < backups[j].BackupId
+	})
+
+	return backups, nil
+}
+
+// deleteFullBackup removes a full backup from the backup location, which must be
+// mounted to this tool at the same path as on solr nodes. Backups of other
+// repositories, e.g. object stores, cannot be removed this way.
+func deleteFullBackup(ctx context.Context, client *Client, config Config, colId, backupId int64) error {
+	if repository := config.repository(colId); repository != "" {
+		return fmt.Errorf("full backups can only be pruned on a locally mounted LocalFileSystemRepository, %s uses repository %s", config.Collections[colId], repository)
+	}
+
+	name := fullBackupName(config.Collections[colId], backupId)
+	dir := filepath.Join(client.locationOrDefault(config.location(colId)), name)
+
+	// refuse to remove anything which does not look like a full backup
+	if _, err := os.Stat(filepath.Join(dir, "backup.properties")); err != nil {
+		return fmt.Errorf("solr cannot delete full backups and %s is not found in the locally mounted location: %v", name, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		klog.Errorf("error: %v", err)
+
+		return err
+	}
+
+	client.journal.record(JournalEntry{
+		RequestId:  client.newRequestId(),
+		Operation:  "deletebackup",
+		Collection: config.Collections[colId],
+		Name:       name,
+		State:      journalDeleted,
+		Message:    "removed " + dir,
+	})
+
+	return nil
+}
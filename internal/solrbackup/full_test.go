@@ -0,0 +1,215 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Full Backup Tests", func() {
+	Context("Full Backup Layout Tests", func() {
+
+		var dir string
+		var server *httptest.Server
+		var mu sync.Mutex
+		var requests []string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+
+			requests = nil
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				q := r.URL.Query()
+
+				if r.URL.Path == "/solr/products/select" {
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"response":{"numFound":3,"start":0,"docs":[]}}`)
+					return
+				}
+
+				switch q.Get("action") {
+				case "BACKUP":
+					requests = append(requests, fmt.Sprintf("BACKUP %s incremental=%s", q.Get("name"), q.Get("incremental")))
+
+					// solr writes backup.properties when a full backup is complete
+					if err := os.MkdirAll(filepath.Join(q.Get("location"), q.Get("name")), 0755); err == nil {
+						ioutil.WriteFile(filepath.Join(q.Get("location"), q.Get("name"), "backup.properties"), nil, 0644)
+					}
+				case "RESTORE":
+					requests = append(requests, fmt.Sprintf("RESTORE %s backupId=%s", q.Get("name"), q.Get("backupId")))
+				case "LIST":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collections":[]}`)
+					return
+				case "REQUESTSTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":""}}`)
+					return
+				}
+
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		newClient := func() (Config, *Client) {
+			config := Config{
+				SolrEndpoint: server.URL,
+				Location:     filepath.Join(dir, "backups"),
+				Collections:  []string{"products"},
+				BackupMode:   BackupModeFull,
+				JournalFile:  filepath.Join(dir, "journal.jsonl"),
+			}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return config, client
+		}
+
+		Describe("Test names", func() {
+			It("Should map full backup names to their base names", func() {
+				id := fullBackupId(time.Date(2022, 10, 1, 1, 2, 3, 0, time.UTC))
+				Expect(id).To(Equal(int64(20221001010203)))
+				Expect(fullBackupName("products", id)).To(Equal("products_full_20221001010203"))
+				Expect(backupBaseName("products_full_20221001010203")).To(Equal("products"))
+				Expect(backupBaseName("products")).To(Equal("products"))
+			})
+		})
+
+		Describe("Test backup, list, restore and delete", func() {
+			It("Should handle full backups by name", func() {
+				config, client := newClient()
+				ctx := context.Background()
+
+				Expect(Backup(ctx, client, config, 0)).To(BeNil(), "Backup returns error")
+				Expect(requests).To(HaveLen(1))
+				Expect(requests[0]).To(MatchRegexp(`^BACKUP products_full_\d{14} incremental=false$`))
+
+				Expect(os.MkdirAll(filepath.Join(config.Location, "products_full_20221001010203"), 0755)).To(BeNil())
+
+				backups, err := backupListRetrive(ctx, client, config, 0)
+				Expect(err).To(BeNil(), "backupListRetrive returns error")
+				Expect(backups).To(HaveLen(1), "incomplete backups should not be listed")
+
+				name := requests[0][len("BACKUP ") : len(requests[0])-len(" incremental=false")]
+				id := backups[0].BackupId
+				Expect(fullBackupName("products", id)).To(Equal(name))
+
+				documents, err := recordedDocuments(client, "products", &id)
+				Expect(err).To(BeNil(), "recordedDocuments returns error")
+				Expect(*documents).To(Equal(int64(3)))
+
+				Expect(Restore(ctx, client, config, 0, RestoreOptions{Force: true})).To(BeNil(), "Restore returns error")
+				Expect(requests[1]).To(Equal(fmt.Sprintf("RESTORE %s backupId=", name)))
+
+				err = BackupDeleteWithColIdWithBackupId(ctx, client, config, 0, 20221001010203)
+				Expect(err).NotTo(BeNil(), "should not delete what does not look like a full backup")
+
+				Expect(BackupDeleteWithColIdWithBackupId(ctx, client, config, 0, id)).To(BeNil(), "delete returns error")
+				Expect(filepath.Join(config.Location, name)).NotTo(BeADirectory())
+
+				backups, err = backupListRetrive(ctx, client, config, 0)
+				Expect(err).To(BeNil(), "backupListRetrive returns error")
+				Expect(backups).To(BeEmpty())
+			})
+		})
+
+		Describe("Test delete from repository", func() {
+			It("Should refuse to remove anything", func() {
+				config, client := newClient()
+				config.Repository = "s3"
+
+				name := fullBackupName("products", 20221001010203)
+				Expect(os.MkdirAll(filepath.Join(config.Location, name), 0755)).To(BeNil())
+				Expect(ioutil.WriteFile(filepath.Join(config.Location, name, "backup.properties"), nil, 0644)).To(BeNil())
+
+				err := BackupDeleteWithColIdWithBackupId(context.Background(), client, config, 0, 20221001010203)
+				Expect(err).To(MatchError(ContainSubstring("locally mounted LocalFileSystemRepository")))
+				Expect(filepath.Join(config.Location, name)).To(BeADirectory())
+			})
+		})
+
+		Describe("Test prune plan of repository", func() {
+			It("Should keep full backups which cannot be deleted", func() {
+				config, client := newClient()
+				config.Repository = "s3"
+				config.Retention = RetentionPolicy{KeepLast: 1}
+
+				for _, backupId := range []int64{20221001010203, 20221002010203} {
+					Expect(client.Journal().Record(JournalEntry{RequestId: fmt.Sprint(backupId), Operation: "backup", Collection: "products", Name: fullBackupName("products", backupId), State: journalSubmitted})).To(BeNil())
+					Expect(client.Journal().Record(JournalEntry{RequestId: fmt.Sprint(backupId), State: journalCompleted})).To(BeNil())
+				}
+
+				plan, err := planCollectionPrune(context.Background(), client, config, 0, time.Now())
+				Expect(err).To(BeNil(), "planCollectionPrune returns error")
+				Expect(plan.Decisions).To(HaveLen(2))
+				Expect(plan.Decisions[0].Rules).To(Equal([]string{"last"}))
+				Expect(plan.Decisions[1].Keep).To(BeTrue())
+				Expect(plan.Decisions[1].Rules).To(Equal([]string{"not prunable in repository s3"}))
+			})
+		})
+
+		Describe("Test listing without journal", func() {
+			It("Should list backups in the location", func() {
+				config, _ := newClient()
+				config.JournalFile = ""
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				for _, name := range []string{"products_full_20221001010203", "products_full_20221002010203", "orders_full_20221001010203"} {
+					Expect(os.MkdirAll(filepath.Join(config.Location, name), 0755)).To(BeNil())
+					Expect(ioutil.WriteFile(filepath.Join(config.Location, name, "backup.properties"), nil, 0644)).To(BeNil())
+				}
+
+				backups, err := backupListRetrive(context.Background(), client, config, 0)
+				Expect(err).To(BeNil(), "backupListRetrive returns error")
+				Expect(backups).To(HaveLen(2))
+				Expect(backups[0].BackupId).To(Equal(int64(20221001010203)))
+				Expect(backups[1].StartTime).To(Equal("2022-10-02T01:02:03Z"))
+				Expect(backups[1].EndTime).NotTo(BeEmpty())
+			})
+
+			It("Should return error for unmounted location and repositories", func() {
+				config, _ := newClient()
+				config.JournalFile = ""
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				_, err = backupListRetrive(context.Background(), client, config, 0)
+				Expect(err).NotTo(BeNil())
+
+				config.Repository = "s3"
+				_, err = backupListRetrive(context.Background(), client, config, 0)
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+	})
+})
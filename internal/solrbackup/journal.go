@@ -0,0 +1,207 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	journalSubmitted = "submitted"
+	journalRejected  = "rejected"
+	journalCompleted = "completed"
+	journalFailed    = "failed"
+	journalNotFound  = "notfound"
+	journalTimeout   = "timeout"
+	journalDeleted   = "deleted"
+)
+
+// JournalEntry records a state change of an async request submitted by this tool.
+type JournalEntry struct {
+	RequestId  string    `json:"requestId"`
+	Operation  string    `json:"operation"`
+	Collection string    `json:"collection"`
+	Name       string    `json:"name,omitempty"`
+	Alias      string    `json:"alias,omitempty"`
+	State      string    `json:"state"`
+	Message    string    `json:"message,omitempty"`
+	Time       time.Time `json:"time"`
+
+	// Documents is the number of documents of the collection when a backup was
+	// submitted and BackupId the backup point it created.
+	Documents *int64 `json:"documents,omitempty"`
+	BackupId  *int64 `json:"backupId,omitempty"`
+
+	// Outcome is the last finished state of the request, it is kept after the
+	// status is deleted.
+	Outcome string `json:"-"`
+}
+
+// Journal is an append only file of async request states, one json entry per line.
+// A nil journal discards records.
+type Journal struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenJournal returns the journal at path, or nil when path is empty.
+func OpenJournal(path string) *Journal {
+	if path == "" {
+		return nil
+	}
+
+	return &Journal{path: path}
+}
+
+// Record appends entry to the journal.
+func (j *Journal) Record(entry JournalEntry) error {
+	if j == nil {
+		return nil
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+
+		return err
+	}
+
+	return f.Close()
+}
+
+// record appends entry and only logs failures, journal problems should not fail
+// the operation itself.
+func (j *Journal) record(entry JournalEntry) {
+	if err := j.Record(entry); err != nil {
+		klog.Errorf("cannot record %s of %s to journal: %v", entry.State, entry.RequestId, err)
+	}
+}
+
+// Entries returns the latest entry of each request in submission order. Fields
+// missing from later entries are filled from earlier ones.
+func (j *Journal) Entries() ([]JournalEntry, error) {
+	if j == nil {
+		return nil, nil
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	f, err := os.Open(j.path)
+
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	latest := make(map[string]*JournalEntry)
+	order := make([]string, 0)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry JournalEntry
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("journal %s line %d: %v", j.path, line, err)
+		}
+
+		prev, ok := latest[entry.RequestId]
+		if !ok {
+			order = append(order, entry.RequestId)
+			latest[entry.RequestId] = &entry
+			continue
+		}
+
+		mergeJournalEntry(prev, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := make([]JournalEntry, 0, len(order))
+	for _, id := range order {
+		entries = append(entries, *latest[id])
+	}
+
+	return entries, nil
+}
+
+func mergeJournalEntry(prev *JournalEntry, entry JournalEntry) {
+	switch entry.State {
+	case journalCompleted, journalFailed, journalNotFound, journalRejected:
+		prev.Outcome = entry.State
+	}
+
+	prev.State = entry.State
+	prev.Message = entry.Message
+	prev.Time = entry.Time
+
+	if entry.Operation != "" {
+		prev.Operation = entry.Operation
+	}
+	if entry.Collection != "" {
+		prev.Collection = entry.Collection
+	}
+	if entry.Name != "" {
+		prev.Name = entry.Name
+	}
+	if entry.Alias != "" {
+		prev.Alias = entry.Alias
+	}
+	if entry.Documents != nil {
+		prev.Documents = entry.Documents
+	}
+	if entry.BackupId != nil {
+		prev.BackupId = entry.BackupId
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = Describe("Journal Tests", func() {
+	Context("Record Tests", func() {
+
+		var dir string
+		var journal *Journal
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+			journal = OpenJournal(filepath.Join(dir, "state", "journal.jsonl"))
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		Describe("Test latest entries", func() {
+			It("Should return latest state of each request", func() {
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", Operation: "backup", Collection: "test", Name: "test", State: journalSubmitted})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-2", Operation: "backup", Collection: "test1", Name: "test1", State: journalSubmitted})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", State: journalCompleted})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", State: journalDeleted})).To(BeNil())
+
+				entries, err := journal.Entries()
+				Expect(err).To(BeNil(), "Entries returns error")
+				Expect(entries).To(HaveLen(2))
+				Expect(entries[0].RequestId).To(Equal("sb-1"))
+				Expect(entries[0].State).To(Equal(journalDeleted))
+				Expect(entries[0].Outcome).To(Equal(journalCompleted))
+				Expect(entries[0].Collection).To(Equal("test"))
+				Expect(entries[1].State).To(Equal(journalSubmitted))
+			})
+		})
+
+		Describe("Test missing journal", func() {
+			It("Should return no entries", func() {
+				entries, err := journal.Entries()
+				Expect(err).To(BeNil())
+				Expect(entries).To(BeEmpty())
+			})
+		})
+
+		Describe("Test disabled journal", func() {
+			It("Should discard records", func() {
+				var disabled *Journal
+				Expect(disabled.Record(JournalEntry{RequestId: "sb-1"})).To(BeNil())
+				entries, err := disabled.Entries()
+				Expect(err).To(BeNil())
+				Expect(entries).To(BeEmpty())
+			})
+		})
+
+	})
+})
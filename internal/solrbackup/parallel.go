@@ -0,0 +1,133 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CollectionErrors aggregates per collection results of an operation run over
+// multiple collections.
+type CollectionErrors struct {
+	Operation string
+	Succeeded []string
+	Failed    map[string]error
+}
+
+func (e *CollectionErrors) Error() string {
+	cols := make([]string, 0, len(e.Failed))
+	for col := range e.Failed {
+		cols = append(cols, col)
+	}
+	sort.Strings(cols)
+
+	failures := make([]string, 0, len(cols))
+	for _, col := range cols {
+		failures = append(failures, fmt.Sprintf("%s: %v", col, e.Failed[col]))
+	}
+
+	return fmt.Sprintf("%s failed for %d of %d collections: %s (succeeded: %s)",
+		e.Operation, len(e.Failed), len(e.Failed)+len(e.Succeeded), strings.Join(failures, "; "), strings.Join(e.Succeeded, ", "))
+}
+
+// collectionBatches groups collections of the same concurrency group into a batch,
+// other collections are batches of their own.
+func collectionBatches(config Config) [][]int64 {
+	batches := make([][]int64, 0, len(config.Collections))
+	groups := make(map[string]int)
+
+	for colId := range config.Collections {
+		group := config.settings(int64(colId)).Group
+
+		if i, ok := groups[group]; ok && group != "" {
+			batches[i] = append(batches[i], int64(colId))
+			continue
+		}
+
+		groups[group] = len(batches)
+		batches = append(batches, []int64{int64(colId)})
+	}
+
+	return batches
+}
+
+// forEachCollection runs fn for every configured collection with at most
+// config.Concurrency collections in parallel, collections of the same concurrency
+// group run one after another. A failing collection does not stop the others,
+// failures are returned together as *CollectionErrors.
+func forEachCollection(ctx context.Context, config Config, operation string, fn func(colId int64) error) error {
+	batches := collectionBatches(config)
+
+	workers := config.Concurrency
+	if workers <= 0 {
+		workers = 1
+	}
+	if workers > len(batches) {
+		workers = len(batches)
+	}
+
+	result := &CollectionErrors{Operation: operation, Failed: make(map[string]error)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	jobs := make(chan []int64)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for batch := range jobs {
+				for _, colId := range batch {
+					col := config.Collections[colId]
+
+					err := ctx.Err()
+					if err == nil {
+						err = fn(colId)
+					}
+
+					mu.Lock()
+					if err != nil {
+						klog.Errorf("%s of %s failed: %v", operation, col, err)
+						result.Failed[col] = err
+					} else {
+						klog.V(1).Infof("%s of %s succeeded", operation, col)
+						result.Succeeded = append(result.Succeeded, col)
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, batch := range batches {
+		jobs <- batch
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	sort.Strings(result.Succeeded)
+
+	if len(result.Failed) > 0 {
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Parallel Collection Tests", func() {
+	Context("For Each Collection Tests", func() {
+
+		var config Config
+		config.Collections = []string{"a", "b", "c", "d", "e"}
+		config.Concurrency = 2
+
+		ctx := context.Background()
+
+		Describe("Test bounded concurrency", func() {
+			It("Should not exceed concurrency", func() {
+				var mu sync.Mutex
+				running, peak := 0, 0
+
+				err := forEachCollection(ctx, config, "backup", func(colId int64) error {
+					mu.Lock()
+					running++
+					if running > peak {
+						peak = running
+					}
+					mu.Unlock()
+
+					time.Sleep(10 * time.Millisecond)
+
+					mu.Lock()
+					running--
+					mu.Unlock()
+					return nil
+				})
+
+				Expect(err).To(BeNil(), "forEachCollection returns error")
+				Expect(peak).To(Equal(2))
+			})
+		})
+
+		Describe("Test failing collections", func() {
+			It("Should continue and aggregate errors", func() {
+				err := forEachCollection(ctx, config, "backup", func(colId int64) error {
+					if config.Collections[colId] == "b" || config.Collections[colId] == "d" {
+						return errors.New("failed")
+					}
+					return nil
+				})
+
+				var colErrs *CollectionErrors
+				Expect(errors.As(err, &colErrs)).To(BeTrue(), "error is not CollectionErrors")
+				Expect(colErrs.Succeeded).To(Equal([]string{"a", "c", "e"}))
+				Expect(colErrs.Failed).To(HaveLen(2))
+				Expect(colErrs.Failed).To(HaveKey("b"))
+				Expect(colErrs.Failed).To(HaveKey("d"))
+			})
+		})
+
+	})
+})
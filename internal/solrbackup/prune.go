@@ -0,0 +1,223 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	"io"
+	klog "k8s.io/klog/v2"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PrunePlan is the retention decision of every backup point of the collections,
+// computed once and applied as is.
+type PrunePlan struct {
+	Time        time.Time             `json:"time"`
+	Location    string                `json:"location"`
+	Collections []CollectionPrunePlan `json:"collections"`
+}
+
+// CollectionPrunePlan holds retention decisions of a collection from the newest
+// backup point to the oldest. Error is set when the collection could not be
+// planned, nothing of it is deleted then.
+type CollectionPrunePlan struct {
+	Collection string              `json:"collection"`
+	Policy     string              `json:"policy"`
+	Decisions  []RetentionDecision `json:"decisions"`
+	Error      string              `json:"error,omitempty"`
+}
+
+func planCollectionPrune(ctx context.Context, client *Client, config Config, colId int64, now time.Time) (CollectionPrunePlan, error) {
+	policy := config.retentionPolicy(colId)
+	plan := CollectionPrunePlan{Collection: config.Collections[colId], Policy: policy.String()}
+
+	// an empty override would otherwise delete every backup point
+	if policy.IsZero() {
+		err := fmt.Errorf("retention policy of %s keeps no backup points", plan.Collection)
+		klog.Errorf("error: %v", err)
+
+		return plan, err
+	}
+
+	backups, err := backupListRetrive(ctx, client, config, colId)
+
+	if err != nil {
+		return plan, err
+	}
+
+	if plan.Decisions, err = policy.Evaluate(backups, now); err != nil {
+		klog.Errorf("error: %v", err)
+
+		return plan, err
+	}
+
+	// full backups can only be deleted from a locally mounted location, so the
+	// plan keeps the ones of a repository instead of failing when it is applied
+	if repository := config.repository(colId); config.backupMode(colId) == BackupModeFull && repository != "" {
+		rule := "not prunable in repository " + repository
+
+		for i := range plan.Decisions {
+			if !plan.Decisions[i].Keep {
+				klog.Warningf("full backup %d of %s is kept, %s", plan.Decisions[i].Backup.BackupId, plan.Collection, rule)
+
+				plan.Decisions[i].Keep = true
+				plan.Decisions[i].Rules = []string{rule}
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+func applyCollectionPrune(ctx context.Context, client *Client, config Config, colId int64, plan CollectionPrunePlan) error {
+	// oldest backup points are deleted first
+	for i := len(plan.Decisions) - 1; i >= 0; i-- {
+		decision := plan.Decisions[i]
+
+		if decision.Keep {
+			klog.V(5).Infof("keeping backup %d of %s by %v", decision.Backup.BackupId, plan.Collection, decision.Rules)
+
+			continue
+		}
+
+		if err := BackupDeleteWithColIdWithBackupId(ctx, client, config, colId, decision.Backup.BackupId); err != nil {
+			return err
+		}
+
+		klog.V(1).Infof("backup %d of %s is deleted", decision.Backup.BackupId, plan.Collection)
+	}
+
+	return nil
+}
+
+// PlanPrune computes retention decisions of the collections without deleting
+// anything. Collections which cannot be planned are recorded with their error in
+// the plan, which is returned together with the *CollectionErrors of them.
+func PlanPrune(ctx context.Context, client *Client, config Config) (*PrunePlan, error) {
+	now := time.Now().UTC()
+	plans := make([]CollectionPrunePlan, len(config.Collections))
+	var mu sync.Mutex
+
+	for colId, col := range config.Collections {
+		plans[colId].Collection = col
+	}
+
+	err := forEachCollection(ctx, config, "prune plan", func(colId int64) error {
+		plan, err := planCollectionPrune(ctx, client, config, colId, now)
+
+		mu.Lock()
+		plans[colId] = plan
+		mu.Unlock()
+
+		return err
+	})
+
+	if errs, ok := err.(*CollectionErrors); ok {
+		for colId := range plans {
+			if failure, ok := errs.Failed[plans[colId].Collection]; ok {
+				plans[colId].Decisions = nil
+				plans[colId].Error = failure.Error()
+			}
+		}
+	}
+
+	return &PrunePlan{Time: now, Location: config.Location, Collections: plans}, err
+}
+
+// ApplyPrunePlan deletes exactly the backup points the plan decided to delete.
+// Collections which failed to be planned are reported as failed without
+// touching them.
+func ApplyPrunePlan(ctx context.Context, client *Client, config Config, plan *PrunePlan) error {
+	if plan.Location != "" && plan.Location != config.Location {
+		return fmt.Errorf("prune plan is made for location %s, not %s", plan.Location, config.Location)
+	}
+
+	config.Collections = make([]string, 0, len(plan.Collections))
+	for _, col := range plan.Collections {
+		config.Collections = append(config.Collections, col.Collection)
+	}
+
+	return forEachCollection(ctx, config, "delete", func(colId int64) error {
+		if plan.Collections[colId].Error != "" {
+			return fmt.Errorf("not planned: %s", plan.Collections[colId].Error)
+		}
+
+		return applyCollectionPrune(ctx, client, config, colId, plan.Collections[colId])
+	})
+}
+
+// ReadPrunePlan reads a plan written by RenderPrunePlan in json format.
+func ReadPrunePlan(r io.Reader) (*PrunePlan, error) {
+	var plan PrunePlan
+
+	if err := json.NewDecoder(r).Decode(&plan); err != nil {
+		return nil, fmt.Errorf("cannot read prune plan: %v", err)
+	}
+
+	return &plan, nil
+}
+
+// RenderPrunePlan writes the plan to stdout as a table or, with "json" format, as
+// json which can be read back by ReadPrunePlan.
+func RenderPrunePlan(plan *PrunePlan, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(plan)
+	case "", "table":
+	default:
+		return fmt.Errorf("unknown prune plan format %q, expected table or json", format)
+	}
+
+	t := prettytable.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(prettytable.Row{"Collection", "#", "Backup Time", "Decision", "Rules"})
+
+	kept, deleted, failed := 0, 0, 0
+
+	for _, col := range plan.Collections {
+		if col.Error != "" {
+			t.AppendRow(prettytable.Row{col.Collection, "", "", "failed", col.Error})
+			failed++
+
+			continue
+		}
+
+		for _, decision := range col.Decisions {
+			action, rules := "delete", "no rule of "+col.Policy
+
+			if decision.Keep {
+				action, rules = "keep", strings.Join(decision.Rules, ", ")
+				kept++
+			} else {
+				deleted++
+			}
+
+			t.AppendRow(prettytable.Row{col.Collection, decision.Backup.BackupId, decision.Backup.StartTime, action, rules})
+		}
+	}
+
+	t.AppendFooter(prettytable.Row{"", "", "", fmt.Sprintf("%d keep, %d delete, %d failed", kept, deleted, failed), ""})
+	t.Render()
+
+	return nil
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+var _ = Describe("Prune Tests", func() {
+	Context("Prune Plan Tests", func() {
+
+		var server *httptest.Server
+		var mu sync.Mutex
+		var deleted []string
+
+		BeforeEach(func() {
+			deleted = nil
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				q := r.URL.Query()
+
+				switch q.Get("action") {
+				case "LISTBACKUP":
+					if q.Get("name") == "broken" {
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprint(w, `{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"backup location is not readable","code":400}}`)
+						return
+					}
+					now := time.Now().UTC()
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"collection":"%s","backups":[{"backupId":0,"startTime":"%s"},{"backupId":1,"startTime":"%s"},{"backupId":2,"startTime":"%s"}]}`,
+						q.Get("name"), now.AddDate(0, 0, -30).Format(time.RFC3339), now.AddDate(0, 0, -20).Format(time.RFC3339), now.AddDate(0, 0, -1).Format(time.RFC3339))
+				case "DELETEBACKUP":
+					if q.Get("purgeUnused") != "true" {
+						deleted = append(deleted, q.Get("name")+"/"+q.Get("backupId"))
+					}
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "REQUESTSTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":""}}`)
+				case "DELETESTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		newClient := func() (Config, *Client) {
+			config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"test", "test1"}, RetaintionDays: 7, Retention: RetentionPolicy{Monthly: 2}}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return config, client
+		}
+
+		Describe("Test plan", func() {
+			It("Should decide without deleting", func() {
+				config, client := newClient()
+
+				plan, err := PlanPrune(context.Background(), client, config)
+				Expect(err).To(BeNil(), "PlanPrune returns error")
+				Expect(plan.Collections).To(HaveLen(2))
+				Expect(plan.Collections[1].Collection).To(Equal("test1"))
+				Expect(plan.Collections[1].Policy).To(Equal("days=7 monthly=2"))
+
+				decisions := plan.Collections[0].Decisions
+				Expect(decisions).To(HaveLen(3))
+				Expect(decisions[0].Backup.BackupId).To(Equal(int64(2)))
+				Expect(decisions[0].Keep).To(BeTrue())
+				Expect(decisions[0].Rules).To(ContainElement("within 7 days"))
+				Expect(deleted).To(BeEmpty())
+			})
+		})
+
+		Describe("Test plan with failing collection", func() {
+			It("Should plan and prune other collections", func() {
+				config, client := newClient()
+				config.Collections = []string{"test", "broken"}
+
+				plan, err := PlanPrune(context.Background(), client, config)
+				Expect(err).To(BeAssignableToTypeOf(&CollectionErrors{}))
+				Expect(err.(*CollectionErrors).Failed).To(HaveKey("broken"))
+				Expect(plan.Collections).To(HaveLen(2))
+				Expect(plan.Collections[0].Decisions).To(HaveLen(3))
+				Expect(plan.Collections[0].Error).To(BeEmpty())
+				Expect(plan.Collections[1].Collection).To(Equal("broken"))
+				Expect(plan.Collections[1].Decisions).To(BeEmpty())
+				Expect(plan.Collections[1].Error).To(ContainSubstring("backup location is not readable"))
+
+				err = ApplyPrunePlan(context.Background(), client, config, plan)
+				Expect(err).To(BeAssignableToTypeOf(&CollectionErrors{}))
+				Expect(err.(*CollectionErrors).Succeeded).To(Equal([]string{"test"}))
+				Expect(err.(*CollectionErrors).Failed).To(HaveKey("broken"))
+				Expect(deleted).To(Equal([]string{"test/0"}))
+			})
+		})
+
+		Describe("Test plan with empty retention override", func() {
+			It("Should refuse to plan the collection", func() {
+				config, client := newClient()
+				config.Overrides = map[string]CollectionConfig{"test1": {Retention: &RetentionPolicy{}}}
+
+				plan, err := PlanPrune(context.Background(), client, config)
+				Expect(err).To(BeAssignableToTypeOf(&CollectionErrors{}))
+				Expect(err.(*CollectionErrors).Succeeded).To(Equal([]string{"test"}))
+				Expect(plan.Collections[1].Error).To(ContainSubstring("keeps no backup points"))
+				Expect(plan.Collections[1].Decisions).To(BeEmpty())
+			})
+		})
+
+		Describe("Test apply saved plan", func() {
+			It("Should delete exactly planned backup points", func() {
+				config, client := newClient()
+
+				plan := &PrunePlan{Location: "/", Collections: []CollectionPrunePlan{
+					{Collection: "test", Decisions: []RetentionDecision{
+						{Backup: BackupPoint{BackupId: 2}, Keep: true, Rules: []string{"last"}},
+						{Backup: BackupPoint{BackupId: 1}},
+						{Backup: BackupPoint{BackupId: 0}},
+					}},
+				}}
+
+				var buf bytes.Buffer
+				Expect(json.NewEncoder(&buf).Encode(plan)).To(BeNil())
+
+				saved, err := ReadPrunePlan(&buf)
+				Expect(err).To(BeNil(), "ReadPrunePlan returns error")
+
+				err = ApplyPrunePlan(context.Background(), client, config, saved)
+				Expect(err).To(BeNil(), "ApplyPrunePlan returns error")
+				Expect(deleted).To(Equal([]string{"test/0", "test/1"}))
+			})
+
+			It("Should refuse plan of another location", func() {
+				config, client := newClient()
+
+				err := ApplyPrunePlan(context.Background(), client, config, &PrunePlan{Location: "/other"})
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+	})
+})
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	klog "k8s.io/klog/v2"
+	"os"
+	"sort"
+	"strings"
+)
+
+// repositoryProbeName is the backup name listed to check that a repository exists.
+const repositoryProbeName = "solr-backup-repository-probe"
+
+// BackupRepository is a backup repository configured in solr.xml.
+type BackupRepository struct {
+	Name       string               `xml:"name,attr"`
+	Class      string               `xml:"class,attr"`
+	Default    bool                 `xml:"default,attr"`
+	Properties []RepositoryProperty `xml:",any"`
+}
+
+// RepositoryProperty is a <str>, <int> or <bool> setting of a backup repository.
+type RepositoryProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Property returns value of the named setting.
+func (r BackupRepository) Property(name string) string {
+	for _, prop := range r.Properties {
+		if prop.Name == name {
+			return strings.TrimSpace(prop.Value)
+		}
+	}
+
+	return ""
+}
+
+// parseBackupRepositories returns backup repositories of solr.xml.
+func parseBackupRepositories(solrXML []byte) ([]BackupRepository, error) {
+	var doc struct {
+		Repositories []BackupRepository `xml:"backup>repository"`
+	}
+
+	if err := xml.Unmarshal(solrXML, &doc); err != nil {
+		return nil, fmt.Errorf("cannot parse solr.xml: %v", err)
+	}
+
+	return doc.Repositories, nil
+}
+
+// configuredRepositories returns the repositories config refers to.
+func configuredRepositories(config Config) []string {
+	set := make(map[string]bool)
+
+	for _, repository := range []string{config.Repository, config.Defaults.Repository} {
+		if repository != "" {
+			set[repository] = true
+		}
+	}
+
+	for _, override := range config.Overrides {
+		if override.Repository != "" {
+			set[override.Repository] = true
+		}
+	}
+
+	repositories := make([]string, 0, len(set))
+	for repository := range set {
+		repositories = append(repositories, repository)
+	}
+	sort.Strings(repositories)
+
+	return repositories
+}
+
+// isUnknownRepository tells whether solr failed because the backup repository is
+// not configured.
+func isUnknownRepository(err error) bool {
+	solrErr, ok := err.(*SolrError)
+
+	return ok && strings.Contains(solrErr.Message, "Could not find a backup repository with name")
+}
+
+// unknownRepositories probes the repositories config refers to and returns the
+// ones solr does not know. Other failures of a probe, e.g. a missing location,
+// show the repository exists.
+func unknownRepositories(ctx context.Context, client *Client, config Config) ([]string, error) {
+	unknown := make([]string, 0)
+
+	for _, repository := range configuredRepositories(config) {
+		err := client.ProbeRepository(ctx, repository)
+
+		if isUnknownRepository(err) {
+			unknown = append(unknown, repository)
+			continue
+		}
+
+		if err != nil {
+			if _, ok := err.(*SolrError); !ok {
+				klog.Errorf("error: %v", err)
+
+				return nil, err
+			}
+		}
+
+		klog.V(1).Infof("backup repository %s is configured", repository)
+	}
+
+	return unknown, nil
+}
+
+// renderRepositories lists backup repositories of solr.xml stored in zookeeper.
+// Solr 9 no longer loads solr.xml from zookeeper, so it is only a detail listing
+// and skipped when solr.xml cannot be read.
+func renderRepositories(ctx context.Context, client *Client) {
+	solrXML, err := client.SolrXML(ctx)
+
+	if err != nil {
+		klog.Warningf("cannot read solr.xml from zookeeper, repositories are not listed: %v", err)
+
+		return
+	}
+
+	repositories, err := parseBackupRepositories(solrXML)
+
+	if err != nil {
+		klog.Warningf("repositories are not listed: %v", err)
+
+		return
+	}
+
+	t := prettytable.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(prettytable.Row{"Name", "Class", "Default", "Location"})
+
+	for _, repository := range repositories {
+		t.AppendRow(prettytable.Row{repository.Name, repository.Class, repository.Default, repository.Property("location")})
+	}
+
+	if len(repositories) == 0 {
+		t.AppendRow(prettytable.Row{"", "LocalFileSystemRepository", true, ""})
+	}
+
+	t.Render()
+}
+
+// Repositories lists backup repositories of solr.xml stored in zookeeper, when it
+// is there, and fails when config refers to a repository solr does not know.
+func Repositories(ctx context.Context, client *Client, config Config) error {
+	renderRepositories(ctx, client)
+
+	unknown, err := unknownRepositories(ctx, client, config)
+
+	if err != nil {
+		return err
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("repositories %s are not configured in solr", strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+)
+
+const testSolrXML = `<?xml version="1.0" encoding="UTF-8" ?>
+<solr>
+  <int name="maxBooleanClauses">${solr.max.booleanClauses:1024}</int>
+  <backup>
+    <repository name="s3" class="org.apache.solr.s3.S3BackupRepository" default="false">
+      <str name="s3.bucket.name">backups</str>
+      <str name="location">/solr</str>
+    </repository>
+    <repository name="local" class="org.apache.solr.core.backup.repository.LocalFileSystemRepository" default="true"/>
+  </backup>
+</solr>`
+
+var _ = Describe("Repository Tests", func() {
+	Context("Backup Repository Tests", func() {
+
+		var server *httptest.Server
+		var backupRepository string
+		var solrXMLInZookeeper bool
+
+		BeforeEach(func() {
+			backupRepository, solrXMLInZookeeper = "", true
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				q := r.URL.Query()
+
+				if r.URL.Path == "/solr/admin/zookeeper" {
+					if !solrXMLInZookeeper {
+						fmt.Fprintf(w, `{"znode":{"path":"%s","prop":{"version":-1}}}`, q.Get("path"))
+						return
+					}
+
+					data, _ := json.Marshal(testSolrXML)
+					fmt.Fprintf(w, `{"znode":{"path":"%s","prop":{"version":0},"data":%s}}`, q.Get("path"), data)
+					return
+				}
+
+				switch q.Get("action") {
+				case "BACKUP":
+					backupRepository = q.Get("repository")
+				case "LISTBACKUP":
+					switch q.Get("repository") {
+					case "s3", "local":
+						w.WriteHeader(http.StatusBadRequest)
+						fmt.Fprintf(w, `{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"No backup name %s found at the location","code":400}}`, q.Get("name"))
+					default:
+						w.WriteHeader(http.StatusInternalServerError)
+						fmt.Fprintf(w, `{"responseHeader":{"status":500,"QTime":1},"error":{"trace":"java.lang.NullPointerException","msg":"Could not find a backup repository with name %s","code":500}}`, q.Get("repository"))
+					}
+					return
+				}
+
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Describe("Test parse solr.xml", func() {
+			It("Should return repositories with their settings", func() {
+				repositories, err := parseBackupRepositories([]byte(testSolrXML))
+				Expect(err).To(BeNil(), "parseBackupRepositories returns error")
+				Expect(repositories).To(HaveLen(2))
+				Expect(repositories[0].Name).To(Equal("s3"))
+				Expect(repositories[0].Default).To(BeFalse())
+				Expect(repositories[0].Property("location")).To(Equal("/solr"))
+				Expect(repositories[1].Default).To(BeTrue())
+			})
+		})
+
+		Describe("Test validate configured repositories", func() {
+			It("Should succeed for known repositories", func() {
+				config := Config{SolrEndpoint: server.URL, Repository: "s3", Overrides: map[string]CollectionConfig{"logs": {Repository: "local"}}}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				Expect(Repositories(context.Background(), client, config)).To(BeNil())
+			})
+
+			It("Should fail for unknown repositories", func() {
+				config := Config{SolrEndpoint: server.URL, Repository: "gcs"}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = Repositories(context.Background(), client, config)
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("gcs"))
+			})
+
+			It("Should probe repositories when solr.xml is not in zookeeper", func() {
+				solrXMLInZookeeper = false
+
+				config := Config{SolrEndpoint: server.URL, Repository: "s3", Overrides: map[string]CollectionConfig{"logs": {Repository: "gcs"}}}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = Repositories(context.Background(), client, config)
+				Expect(err).To(MatchError("repositories gcs are not configured in solr"))
+			})
+		})
+
+		Describe("Test repository parameter", func() {
+			It("Should be sent on backup", func() {
+				config := Config{SolrEndpoint: server.URL, Location: "/", Repository: "s3", Collections: []string{"test"}}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				Expect(StartBackup(context.Background(), client, config, 0, client.newRequestId())).To(BeNil())
+				Expect(backupRepository).To(Equal("s3"))
+			})
+		})
+
+	})
+})
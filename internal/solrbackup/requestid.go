@@ -0,0 +1,130 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"crypto/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	requestIdPrefix   = "sb-"
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	maxScopeLength    = 24
+)
+
+// newRequestId returns a unique async request id in form sb-<scope>-<ulid>. Scope
+// defaults to host name, so ids of different pods are told apart, and the ulid keeps
+// ids unique within a run.
+func newRequestId(scope string) string {
+	if scope == "" {
+		scope, _ = os.Hostname()
+	}
+
+	scope = sanitizeScope(scope)
+
+	if scope == "" {
+		return requestIdPrefix + newULID(time.Now())
+	}
+
+	return requestIdPrefix + scope + "-" + newULID(time.Now())
+}
+
+func sanitizeScope(scope string) string {
+	var b strings.Builder
+
+	for _, r := range strings.ToLower(scope) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+
+	s := strings.Trim(b.String(), "-")
+	if len(s) > maxScopeLength {
+		s = strings.Trim(s[len(s)-maxScopeLength:], "-")
+	}
+
+	return s
+}
+
+// newULID returns a ulid: 48 bit millisecond timestamp and 80 random bits encoded
+// as 26 crockford base32 characters.
+func newULID(t time.Time) string {
+	var id [16]byte
+
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+
+	// 128 bits are encoded from the most significant side in 5 bit groups, the
+	// first character carries the leading 3 bits.
+	out := make([]byte, 26)
+	var acc uint32
+	bits := 2
+	pos := 0
+
+	for _, b := range id {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockfordAlphabet[(acc>>uint(bits))&0x1f]
+			pos++
+		}
+	}
+
+	return string(out)
+}
+
+// requestIdTime returns creation time of a request id of this tool. Both ulid ids
+// and legacy sb-<unix millis> ids are understood.
+func requestIdTime(id string) (time.Time, bool) {
+	if !strings.HasPrefix(id, requestIdPrefix) {
+		return time.Time{}, false
+	}
+
+	suffix := id[strings.LastIndex(id, "-")+1:]
+
+	if len(suffix) == 26 {
+		var ms int64
+
+		for _, c := range suffix[:10] {
+			v := strings.IndexRune(crockfordAlphabet, c)
+			if v < 0 {
+				return time.Time{}, false
+			}
+			ms = ms<<5 | int64(v)
+		}
+
+		return time.Unix(0, ms*int64(time.Millisecond)), true
+	}
+
+	if ms, err := strconv.ParseInt(suffix, 10, 64); err == nil && ms > 0 {
+		return time.Unix(0, ms*int64(time.Millisecond)), true
+	}
+
+	return time.Time{}, false
+}
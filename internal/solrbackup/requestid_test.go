@@ -0,0 +1,85 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"strings"
+	"time"
+)
+
+var _ = Describe("Request Id Tests", func() {
+	Context("New Request Id Tests", func() {
+
+		Describe("Test format", func() {
+			It("Should contain prefix, scope and ulid", func() {
+				id := newRequestId("Solr-Backup_27212.abc")
+				Expect(id).To(HavePrefix("sb-solr-backup-27212-abc-"))
+				ulid := id[strings.LastIndex(id, "-")+1:]
+				Expect(ulid).To(HaveLen(26))
+				Expect(ulid).To(MatchRegexp("^[0-9A-HJKMNP-TV-Z]+$"))
+			})
+
+			It("Long scope should be truncated", func() {
+				id := newRequestId("solr-backup-nightly-cronjob-27212345-abcde")
+				Expect(len(id)).To(BeNumerically("<=", len(requestIdPrefix)+maxScopeLength+1+26))
+			})
+		})
+
+		Describe("Test uniqueness", func() {
+			It("Ids should not collide in the same millisecond", func() {
+				ids := make(map[string]bool)
+				for i := 0; i < 10000; i++ {
+					ids[newRequestId("test")] = true
+				}
+				Expect(ids).To(HaveLen(10000))
+			})
+
+			It("Ulids should sort by time", func() {
+				t := time.Now()
+				Expect(newULID(t) < newULID(t.Add(time.Millisecond))).To(BeTrue())
+			})
+		})
+
+	})
+
+	Context("Request Id Time Tests", func() {
+
+		Describe("Test ulid id", func() {
+			It("Should return creation time", func() {
+				t := time.Now().Truncate(time.Millisecond)
+				created, ok := requestIdTime("sb-test-" + newULID(t))
+				Expect(ok).To(BeTrue())
+				Expect(created.Equal(t)).To(BeTrue())
+			})
+		})
+
+		Describe("Test legacy id", func() {
+			It("Should return creation time", func() {
+				created, ok := requestIdTime("sb-1645000000000")
+				Expect(ok).To(BeTrue())
+				Expect(created.Unix()).To(Equal(int64(1645000000)))
+			})
+		})
+
+		Describe("Test foreign id", func() {
+			It("Should not return time", func() {
+				_, ok := requestIdTime("1000")
+				Expect(ok).To(BeFalse())
+			})
+		})
+
+	})
+})
@@ -0,0 +1,170 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ResponseHeader struct {
+	Status int `json:"status"`
+	QTime  int `json:"QTime"`
+}
+
+// ErrorInfo is the error section of a failed solr response. Older solr versions
+// return it as a bare message string, which is kept in Msg.
+type ErrorInfo struct {
+	Msg      string   `json:"msg"`
+	Code     int      `json:"code"`
+	Metadata []string `json:"metadata"`
+	Trace    string   `json:"trace"`
+}
+
+func (e *ErrorInfo) UnmarshalJSON(data []byte) error {
+	var msg string
+	if err := json.Unmarshal(data, &msg); err == nil {
+		e.Msg = msg
+
+		return nil
+	}
+
+	type errorInfo ErrorInfo
+
+	return json.Unmarshal(data, (*errorInfo)(e))
+}
+
+// ExceptionInfo is reported by collections api operations which fail at overseer.
+type ExceptionInfo struct {
+	Msg     string `json:"msg"`
+	RspCode int    `json:"rspCode"`
+}
+
+// Response is the envelope shared by all collections api responses.
+type Response struct {
+	ResponseHeader ResponseHeader `json:"responseHeader"`
+	Error          *ErrorInfo     `json:"error,omitempty"`
+	Exception      *ExceptionInfo `json:"exception,omitempty"`
+}
+
+type AsyncResponse struct {
+	Response
+	RequestId string `json:"requestid"`
+}
+
+type BackupPoint struct {
+	BackupId        int64   `json:"backupId"`
+	IndexVersion    string  `json:"indexVersion"`
+	StartTime       string  `json:"startTime"`
+	EndTime         string  `json:"endTime"`
+	IndexFileCount  int64   `json:"indexFileCount"`
+	IndexSizeMB     float64 `json:"indexSizeMB"`
+	ConfigName      string  `json:"collection.configName"`
+	CollectionAlias string  `json:"collectionAlias"`
+}
+
+// Time returns start time of the backup point.
+func (b BackupPoint) Time() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, b.StartTime)
+}
+
+type ListBackupsResponse struct {
+	Response
+	Collection string        `json:"collection"`
+	Backups    []BackupPoint `json:"backups"`
+}
+
+type ListCollectionsResponse struct {
+	Response
+	Collections []string `json:"collections"`
+}
+
+type ListAliasesResponse struct {
+	Response
+	// Aliases maps alias names to comma separated collection names.
+	Aliases map[string]string `json:"aliases"`
+}
+
+type RequestStatus struct {
+	State string `json:"state"`
+	Msg   string `json:"msg"`
+}
+
+type RequestStatusResponse struct {
+	Response
+	Status RequestStatus `json:"status"`
+	// Failure holds per node (or shard) failures of the task.
+	Failure map[string]json.RawMessage `json:"failure"`
+
+	// Raw is the undecoded response, it carries the operation specific results.
+	Raw json.RawMessage `json:"-"`
+}
+
+type DeletedBackup struct {
+	BackupId  int64  `json:"backupId"`
+	StartTime string `json:"startTime"`
+	Size      int64  `json:"size"`
+	NumFiles  int64  `json:"numFiles"`
+}
+
+// DeleteBackupResponse is the result of an async DELETEBACKUP, solr returns it in
+// the REQUESTSTATUS response of the completed request.
+type DeleteBackupResponse struct {
+	Response
+	Collection string          `json:"collection"`
+	Deleted    []DeletedBackup `json:"deleted"`
+}
+
+type ReplicaStatus struct {
+	Core     string `json:"core"`
+	NodeName string `json:"node_name"`
+	State    string `json:"state"`
+	Leader   string `json:"leader"`
+	Type     string `json:"type"`
+}
+
+type ShardStatus struct {
+	State    string                   `json:"state"`
+	Replicas map[string]ReplicaStatus `json:"replicas"`
+}
+
+type CollectionStatus struct {
+	ConfigName string                 `json:"configName"`
+	Shards     map[string]ShardStatus `json:"shards"`
+	Aliases    []string               `json:"aliases"`
+}
+
+type ClusterStatusResponse struct {
+	Response
+	Cluster struct {
+		Collections map[string]CollectionStatus `json:"collections"`
+		LiveNodes   []string                    `json:"live_nodes"`
+	} `json:"cluster"`
+}
+
+// ZookeeperResponse is a znode read through the zookeeper admin handler.
+type ZookeeperResponse struct {
+	Response
+	Znode struct {
+		Path string `json:"path"`
+		Data string `json:"data"`
+	} `json:"znode"`
+}
+
+type QueryResponse struct {
+	Response
+	Result struct {
+		NumFound int64 `json:"numFound"`
+	} `json:"response"`
+}
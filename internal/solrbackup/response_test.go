@@ -0,0 +1,81 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"encoding/json"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Response Model Tests", func() {
+	Context("Decode Tests", func() {
+
+		Describe("Test error envelope", func() {
+			It("Error object should be decoded", func() {
+				var resp Response
+				err := json.Unmarshal([]byte(`{"responseHeader":{"status":400,"QTime":1},"error":{"metadata":["error-class","org.apache.solr.common.SolrException"],"msg":"Task with the same requestid already exists.","code":400}}`), &resp)
+				Expect(err).To(BeNil(), "unmarshal returns error")
+				Expect(resp.ResponseHeader.Status).To(Equal(400))
+				Expect(resp.Error).NotTo(BeNil())
+				Expect(resp.Error.Msg).To(Equal("Task with the same requestid already exists."))
+				Expect(resp.Error.Code).To(Equal(400))
+			})
+
+			It("Error string should be decoded", func() {
+				var resp Response
+				err := json.Unmarshal([]byte(`{"responseHeader":{"status":0,"QTime":1},"error":"Task with the same requestid already exists."}`), &resp)
+				Expect(err).To(BeNil(), "unmarshal returns error")
+				Expect(resp.Error).NotTo(BeNil())
+				Expect(resp.Error.Msg).To(Equal("Task with the same requestid already exists."))
+			})
+		})
+
+		Describe("Test list backup", func() {
+			It("Backup points should be decoded", func() {
+				var resp ListBackupsResponse
+				err := json.Unmarshal([]byte(`{"responseHeader":{"status":0,"QTime":4},"collection":"test","backups":[{"indexFileCount":0,"indexSizeMB":0.0,"shardBackupIds":{},"collection.configName":"test","backupId":3,"collectionAlias":"test","startTime":"2022-02-09T03:19:52.085653Z","indexVersion":"8.11.1"}]}`), &resp)
+				Expect(err).To(BeNil(), "unmarshal returns error")
+				Expect(resp.Backups).To(HaveLen(1))
+				Expect(resp.Backups[0].BackupId).To(Equal(int64(3)))
+				Expect(resp.Backups[0].ConfigName).To(Equal("test"))
+
+				t, err := resp.Backups[0].Time()
+				Expect(err).To(BeNil(), "start time cannot be parsed")
+				Expect(t.Year()).To(Equal(2022))
+			})
+
+			It("Unexpected shape should return error", func() {
+				var resp ListBackupsResponse
+				err := json.Unmarshal([]byte(`{"backups":[{"backupId":"x"}]}`), &resp)
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+		Describe("Test delete backup", func() {
+			It("Deleted backup points should be decoded from request status", func() {
+				raw := []byte(`{"responseHeader":{"status":0,"QTime":2},"collection":"test","deleted":[{"startTime":"2022-02-09T03:19:52.085653Z","backupId":1,"size":2048,"numFiles":12}],"status":{"state":"completed","msg":"found [test-1] in completed tasks"}}`)
+
+				var resp RequestStatusResponse
+				Expect(json.Unmarshal(raw, &resp)).To(BeNil(), "unmarshal returns error")
+				resp.Raw = raw
+
+				deleted, err := deletedBackups(&resp)
+				Expect(err).To(BeNil(), "deletedBackups returns error")
+				Expect(deleted).To(Equal([]DeletedBackup{{BackupId: 1, StartTime: "2022-02-09T03:19:52.085653Z", Size: 2048, NumFiles: 12}}))
+			})
+		})
+
+	})
+})
@@ -14,29 +14,140 @@ limitations under the License.
 package solrbackup
 
 import (
+	"context"
 	"fmt"
 	klog "k8s.io/klog/v2"
 	"time"
 )
 
-func StartRestoreInplace(config Config, colId, reqId int64) error {
+// RestoreOptions controls where and how a backup is restored.
+type RestoreOptions struct {
+	// TargetCollection is the collection to restore into, it defaults to the backed
+	// up collection so the restore is done in place.
+	TargetCollection string
+	// BackupId selects the backup point to restore.
+	BackupId *int64
+	// Before selects the latest backup point started before it, when BackupId is nil.
+	Before time.Time
+	CreateOptions
+	// Verify checks the restored collection when it is not nil.
+	Verify *VerifyOptions
+	// Force restores over a collection which has documents without asking Confirm.
+	Force bool
+	// Confirm is asked before restoring over a collection which has documents. The
+	// restore is refused when it is nil or returns false.
+	Confirm func(plan RestorePlan) (bool, error)
+	// SafetyBackup backs up an existing target collection under the
+	// <name>_before_restore backup name before it is overwritten.
+	SafetyBackup bool
+}
 
-	col := config.Collections[colId]
+// RestorePlan describes which backup point is restored into which collection.
+type RestorePlan struct {
+	Name       string
+	BackupId   int64
+	Location   string
+	Repository string
+	Collection string
+	// Exists tells whether the collection exists, and Documents how many documents
+	// it has then.
+	Exists    bool
+	Documents int64
+}
 
-	backup_uri := fmt.Sprintf("%s%s?action=RESTORE&async=sb-%d&collection=%s&name=%s&location=%s", config.SolrEndpoint, collection_api, reqId, col, col, config.Location)
-	klog.V(5).Infof("backup uri: %v", backup_uri)
+func (p RestorePlan) String() string {
+	location := p.Location
+	if p.Repository != "" {
+		location = fmt.Sprintf("%s of repository %s", p.Location, p.Repository)
+	}
 
-	resp, err := sendRequest(backup_uri)
+	return fmt.Sprintf("backup %s id %d at %s into collection %s", p.Name, p.BackupId, location, p.Collection)
+}
+
+// resolveBackupId returns the backup point selected by opts, or nil when the latest
+// one should be restored.
+func resolveBackupId(ctx context.Context, client *Client, config Config, colId int64, opts RestoreOptions) (*int64, error) {
+	if opts.BackupId != nil || opts.Before.IsZero() {
+		return opts.BackupId, nil
+	}
+
+	backups, err := backupListRetrive(ctx, client, config, colId)
 
 	if err != nil {
-		klog.Errorf("error: %v", err)
+		return nil, err
+	}
+
+	var selected *BackupPoint
+
+	for i, backup := range backups {
+		t, err := backup.Time()
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse start time of backup %d: %v", backup.BackupId, err)
+		}
+
+		if t.Before(opts.Before) && (selected == nil || backup.BackupId > selected.BackupId) {
+			selected = &backups[i]
+		}
+	}
+
+	if selected == nil {
+		return nil, fmt.Errorf("no backup of %s found before %v", config.Collections[colId], opts.Before.Format(time.RFC3339))
+	}
+
+	klog.V(1).Infof("backup %d of %s started at %s is selected", selected.BackupId, config.Collections[colId], selected.StartTime)
+
+	return &selected.BackupId, nil
+}
+
+// restoreTarget returns the collection which the named backup is restored into.
+func restoreTarget(client *Client, config Config, colId int64, opts RestoreOptions) string {
+	if opts.TargetCollection != "" {
+		return opts.TargetCollection
+	}
+
+	_, target := aliasOf(client, config, config.Collections[colId])
+
+	return target
+}
 
+func StartRestore(ctx context.Context, client *Client, config Config, colId int64, reqId string, opts RestoreOptions) error {
+
+	col := config.Collections[colId]
+
+	backupId, err := resolveBackupId(ctx, client, config, colId, opts)
+
+	if err != nil {
 		return err
 	}
 
-	v, ok := resp["error"]
-	if ok && v == "Task with the same requestid already exists." {
-		klog.Error("error: %v", v)
+	if config.backupMode(colId) == BackupModeFull {
+		// full backups are restored by their name
+		if backupId == nil {
+			if backupId, err = latestBackupId(ctx, client, config, colId); err != nil {
+				return err
+			}
+
+			if backupId == nil {
+				return fmt.Errorf("no full backup of %s found", col)
+			}
+		}
+
+		col = fullBackupName(col, *backupId)
+		backupId = nil
+	}
+
+	params := RestoreParams{
+		Collection:    restoreTarget(client, config, colId, opts),
+		Name:          col,
+		Location:      config.location(colId),
+		Repository:    config.repository(colId),
+		BackupId:      backupId,
+		CreateOptions: opts.CreateOptions.merge(config.settings(colId).Restore),
+	}
+
+	if err := client.Restore(ctx, reqId, params); err != nil {
+		klog.Errorf("error: %v", err)
 
 		return err
 	}
@@ -44,30 +155,240 @@ func StartRestoreInplace(config Config, colId, reqId int64) error {
 	return nil
 }
 
-func RestoreInplace(config Config, colId int64) error {
-	reqId := time.Now().UnixMilli()
+func StartRestoreInplace(ctx context.Context, client *Client, config Config, colId int64, reqId string) error {
+	return StartRestore(ctx, client, config, colId, reqId, RestoreOptions{})
+}
+
+// restore restores the backup and waits until it finishes. It returns the restored
+// backup point, nil when the latest one is restored.
+func restore(ctx context.Context, client *Client, config Config, colId int64, opts RestoreOptions) (*int64, error) {
+	reqId := client.newRequestId()
+
+	backupId, err := resolveBackupId(ctx, client, config, colId, opts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	opts.BackupId = backupId
+
+	if err := StartRestore(ctx, client, config, colId, reqId, opts); err != nil {
+		return nil, err
+	}
+
+	if err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.RestoreTimeout)); err != nil {
+		return nil, err
+	}
+
+	return backupId, deleteRequestId(ctx, client, reqId)
+}
+
+// planRestore resolves the backup point to restore and inspects the target collection.
+func planRestore(ctx context.Context, client *Client, config Config, colId int64, opts RestoreOptions) (RestorePlan, error) {
+	plan := RestorePlan{
+		Name:       config.Collections[colId],
+		Location:   client.locationOrDefault(config.location(colId)),
+		Repository: config.repository(colId),
+		Collection: restoreTarget(client, config, colId, opts),
+	}
+
+	backupId, err := resolveBackupId(ctx, client, config, colId, opts)
+
+	if err != nil {
+		return plan, err
+	}
+
+	if backupId == nil {
+		if backupId, err = latestBackupId(ctx, client, config, colId); err != nil {
+			return plan, err
+		}
+
+		if backupId == nil {
+			return plan, fmt.Errorf("no backup of %s found at %s", plan.Name, plan.Location)
+		}
+	}
+
+	plan.BackupId = *backupId
+
+	cols, err := client.ListCollections(ctx)
+
+	if err != nil {
+		return plan, err
+	}
+
+	for _, col := range cols {
+		if col == plan.Collection {
+			plan.Exists = true
+		}
+	}
+
+	if plan.Exists {
+		if plan.Documents, err = client.Count(ctx, plan.Collection, "*:*"); err != nil {
+			return plan, err
+		}
+	}
+
+	return plan, nil
+}
+
+// guardRestore refuses to overwrite a collection which has documents unless the
+// restore is forced or confirmed.
+func guardRestore(plan RestorePlan, opts RestoreOptions) error {
+	if !plan.Exists || plan.Documents == 0 || opts.Force {
+		return nil
+	}
+
+	if opts.Confirm != nil {
+		confirmed, err := opts.Confirm(plan)
+
+		if err != nil {
+			return err
+		}
+
+		if confirmed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("refusing to restore over collection %s which has %d documents, restore is not forced or confirmed", plan.Collection, plan.Documents)
+}
+
+// safetyBackupOperation is the journaled operation of safety backups, so they are
+// not taken for backups of the configured names.
+const safetyBackupOperation = "safetybackup"
+
+// safetyBackupConfig returns config of the safety backups of the collection, which
+// keeps only the newest of them.
+func safetyBackupConfig(config Config, colId int64) Config {
+	name := config.Collections[colId] + "_before_restore"
+
+	settings := config.settings(colId)
+	settings.Retention = &RetentionPolicy{KeepLast: 1}
+
+	config.Collections = []string{name}
+	config.Overrides = map[string]CollectionConfig{name: settings}
+
+	return config
+}
+
+// pruneSafetyBackups deletes safety backups of the collection except the newest.
+// Full safety backups in a backup repository cannot be deleted by this tool.
+func pruneSafetyBackups(ctx context.Context, client *Client, config Config, colId int64) error {
+	safety := safetyBackupConfig(config, colId)
+
+	if safety.backupMode(0) == BackupModeFull && safety.repository(0) != "" {
+		klog.Warningf("full safety backups in repository %s are not pruned, older %s backups should be removed manually", safety.repository(0), safety.Collections[0])
+
+		return nil
+	}
+
+	plan, err := planCollectionPrune(ctx, client, safety, 0, time.Now())
 
-	if err := StartRestoreInplace(config, colId, reqId); err != nil {
+	if err != nil {
 		return err
 	}
 
-	if err := waitRequestStatus(config, reqId); err != nil {
+	return applyCollectionPrune(ctx, client, safety, 0, plan)
+}
+
+// safetyBackup backs up the collection which is about to be overwritten. Only the
+// newest safety backup is kept, older ones are deleted once it is taken.
+func safetyBackup(ctx context.Context, client *Client, config Config, colId int64, plan RestorePlan) error {
+	reqId := client.newRequestId()
+	params := BackupParams{Collection: plan.Collection, Name: plan.Name + "_before_restore", Location: plan.Location, Repository: plan.Repository, Safety: true}
+
+	if config.backupMode(colId) == BackupModeFull {
+		params.Name = fullBackupName(params.Name, fullBackupId(time.Now()))
+		params.Full = true
+	}
+
+	if err := client.Backup(ctx, reqId, params); err != nil {
+		klog.Errorf("error: %v", err)
+
 		return err
 	}
 
-	if err := deleteRequestId(config, reqId); err != nil {
+	if err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.BackupTimeout)); err != nil {
+		return fmt.Errorf("safety backup of %s failed, restore is not started: %w", plan.Collection, err)
+	}
+
+	fmt.Printf("collection %s is backed up as %s at %s\n", plan.Collection, params.Name, plan.Location)
+
+	if err := deleteRequestId(ctx, client, reqId); err != nil {
 		return err
 	}
 
+	if err := pruneSafetyBackups(ctx, client, config, colId); err != nil {
+		klog.Warningf("cannot delete older safety backups of %s: %v", plan.Collection, err)
+	}
+
 	return nil
 }
 
-func RestoreAllInplace(config Config) error {
-	for colId, _ := range config.Collections {
-		if err := RestoreInplace(config, int64(colId)); err != nil {
+func Restore(ctx context.Context, client *Client, config Config, colId int64, opts RestoreOptions) error {
+	plan, err := planRestore(ctx, client, config, colId, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if err := guardRestore(plan, opts); err != nil {
+		return err
+	}
+
+	if opts.SafetyBackup && plan.Exists {
+		if err := safetyBackup(ctx, client, config, colId, plan); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("restoring %s\n", plan)
+
+	opts.BackupId = &plan.BackupId
+
+	backupId, err := restore(ctx, client, config, colId, opts)
+
+	if err != nil {
+		return err
+	}
+
+	target := plan.Collection
+
+	if opts.Verify != nil {
+		if err := Verify(ctx, client, config, colId, target, backupId, *opts.Verify); err != nil {
 			return err
 		}
 	}
 
+	if config.RestoreAliases {
+		if alias, _ := aliasOf(client, config, config.Collections[colId]); alias != "" {
+			klog.V(1).Infof("pointing alias %s to restored collection %s", alias, target)
+
+			if err := client.CreateAlias(ctx, alias, []string{target}); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
+
+// RestoreInplace restores the latest backup over the collection, which is
+// overwritten without confirmation.
+func RestoreInplace(ctx context.Context, client *Client, config Config, colId int64) error {
+	return Restore(ctx, client, config, colId, RestoreOptions{Force: true})
+}
+
+func RestoreAll(ctx context.Context, client *Client, config Config, opts RestoreOptions) error {
+	if opts.TargetCollection != "" && len(config.Collections) != 1 {
+		return fmt.Errorf("target collection can only be given for a single collection, got %d", len(config.Collections))
+	}
+
+	return forEachCollection(ctx, config, "restore", func(colId int64) error {
+		return Restore(ctx, client, config, colId, opts)
+	})
+}
+
+func RestoreAllInplace(ctx context.Context, client *Client, config Config) error {
+	return RestoreAll(ctx, client, config, RestoreOptions{Force: true})
+}
@@ -14,8 +14,16 @@ limitations under the License.
 package solrbackup
 
 import (
+	"context"
+	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"time"
 )
 
@@ -28,38 +36,212 @@ var _ = Describe("Restore Methods Tests", func() {
 		config.Location = "/"
 		config.RetaintionDays = 5
 
-		reqId := time.Now().UnixMilli()
+		ctx := context.Background()
+		client, _ := NewClient(config)
+
+		reqId := client.newRequestId()
 
 		Describe("Test restore single manually", func() {
 			It("StartRestoreInplace should be succeed", func() {
-				err := StartRestoreInplace(config, 0, reqId)
+				err := StartRestoreInplace(ctx, client, config, 0, reqId)
 				Expect(err).To(BeNil(), "start restore returns error")
 			})
 
 			It("waitRequestStatus should be succeed", func() {
-				err := waitRequestStatus(config, reqId)
+				err := waitRequestStatus(ctx, client, reqId, config.waitOptions(config.RestoreTimeout))
 				Expect(err).To(BeNil(), "waitRequestStatus returns error")
 			})
 
 			It("deleteRequestId should be succeed", func() {
-				err := deleteRequestId(config, reqId)
+				err := deleteRequestId(ctx, client, reqId)
 				Expect(err).To(BeNil(), "deleteRequestId returns error")
 			})
 		})
 
 		Describe("Test restore single together", func() {
 			It("RestoreInplace should be succeed", func() {
-				err := RestoreInplace(config, 0)
+				err := RestoreInplace(ctx, client, config, 0)
 				Expect(err).To(BeNil(), "Backup returns error")
 			})
 		})
 
+		Describe("Test restore into new collection", func() {
+			It("Restore should be succeed", func() {
+				opts := RestoreOptions{TargetCollection: "test_restored"}
+				opts.ReplicationFactor = 1
+				err := Restore(ctx, client, config, 0, opts)
+				Expect(err).To(BeNil(), "Restore returns error")
+			})
+		})
+
 		Describe("Test restore all together", func() {
 			It("RestoreAllInplace should be succeed", func() {
-				err := RestoreAllInplace(config)
+				err := RestoreAllInplace(ctx, client, config)
 				Expect(err).To(BeNil(), "BackupAll returns error")
 			})
 		})
 
 	})
+
+	Context("Backup Point Selection Tests", func() {
+
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collection":"test","backups":[
+					{"backupId":0,"startTime":"2022-09-29T01:00:00.000000Z"},
+					{"backupId":1,"startTime":"2022-09-30T01:00:00.000000Z"},
+					{"backupId":2,"startTime":"2022-10-01T01:00:00.000000Z"}]}`)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		selectBackup := func(opts RestoreOptions) (*int64, error) {
+			config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"test"}}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return resolveBackupId(context.Background(), client, config, 0, opts)
+		}
+
+		Describe("Test before timestamp", func() {
+			It("Should select latest backup before it", func() {
+				backupId, err := selectBackup(RestoreOptions{Before: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)})
+				Expect(err).To(BeNil(), "resolveBackupId returns error")
+				Expect(*backupId).To(Equal(int64(1)))
+			})
+
+			It("Should fail when there is no backup before it", func() {
+				_, err := selectBackup(RestoreOptions{Before: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)})
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+		Describe("Test explicit backup id", func() {
+			It("Should be used as is", func() {
+				id := int64(0)
+				backupId, err := selectBackup(RestoreOptions{BackupId: &id})
+				Expect(err).To(BeNil(), "resolveBackupId returns error")
+				Expect(*backupId).To(Equal(int64(0)))
+			})
+		})
+
+		Describe("Test latest", func() {
+			It("Should leave selection to solr", func() {
+				backupId, err := selectBackup(RestoreOptions{})
+				Expect(err).To(BeNil(), "resolveBackupId returns error")
+				Expect(backupId).To(BeNil())
+			})
+		})
+
+	})
+
+	Context("Restore Guard Tests", func() {
+
+		var server *httptest.Server
+		var mu sync.Mutex
+		var actions []string
+
+		BeforeEach(func() {
+			actions = nil
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				q := r.URL.Query()
+
+				if r.URL.Path == "/solr/test/select" {
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"response":{"numFound":5,"start":0,"docs":[]}}`)
+					return
+				}
+
+				switch q.Get("action") {
+				case "LISTBACKUP":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collection":"test","backups":[{"backupId":1,"startTime":"2022-10-01T00:00:00Z"},{"backupId":2,"startTime":"2022-10-02T00:00:00Z"}]}`)
+				case "LIST":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collections":["test"]}`)
+				case "BACKUP":
+					actions = append(actions, "BACKUP "+q.Get("collection")+" as "+q.Get("name"))
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "RESTORE":
+					actions = append(actions, "RESTORE "+q.Get("name")+" id "+q.Get("backupId")+" into "+q.Get("collection"))
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "DELETEBACKUP":
+					if q.Get("purgeUnused") != "true" {
+						actions = append(actions, "DELETEBACKUP "+q.Get("name")+" id "+q.Get("backupId"))
+					}
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "REQUESTSTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":""}}`)
+				case "DELETESTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		restoreWith := func(opts RestoreOptions) error {
+			config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"test"}}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return Restore(context.Background(), client, config, 0, opts)
+		}
+
+		Describe("Test restore over non-empty collection", func() {
+			It("Should be refused without force or confirmation", func() {
+				err := restoreWith(RestoreOptions{})
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("has 5 documents"))
+				Expect(actions).To(BeEmpty())
+			})
+
+			It("Should be refused when not confirmed", func() {
+				var asked RestorePlan
+				err := restoreWith(RestoreOptions{Confirm: func(plan RestorePlan) (bool, error) {
+					asked = plan
+					return false, nil
+				}})
+				Expect(err).NotTo(BeNil())
+				Expect(asked).To(Equal(RestorePlan{Name: "test", BackupId: 2, Location: "/", Collection: "test", Exists: true, Documents: 5}))
+				Expect(actions).To(BeEmpty())
+			})
+
+			It("Should restore pinned latest backup when forced", func() {
+				err := restoreWith(RestoreOptions{Force: true})
+				Expect(err).To(BeNil(), "Restore returns error")
+				Expect(actions).To(Equal([]string{"RESTORE test id 2 into test"}))
+			})
+		})
+
+		Describe("Test safety backup", func() {
+			It("Should back up collection before restoring over it and keep only newest safety backup", func() {
+				dir, err := ioutil.TempDir("", "solr-backup")
+				Expect(err).To(BeNil(), "cannot create temp dir")
+				defer os.RemoveAll(dir)
+
+				config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"test"}, JournalFile: filepath.Join(dir, "journal.jsonl")}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = Restore(context.Background(), client, config, 0, RestoreOptions{SafetyBackup: true, Confirm: func(plan RestorePlan) (bool, error) {
+					return true, nil
+				}})
+				Expect(err).To(BeNil(), "Restore returns error")
+				Expect(actions).To(Equal([]string{"BACKUP test as test_before_restore", "DELETEBACKUP test_before_restore id 1", "RESTORE test id 2 into test"}))
+
+				entries, err := client.Journal().Entries()
+				Expect(err).To(BeNil(), "Entries returns error")
+				Expect(entries[0].Operation).To(Equal(safetyBackupOperation))
+				Expect(missingBackups(entries, []string{"test"}, []string{"test"})).To(BeEmpty())
+			})
+		})
+
+	})
 })
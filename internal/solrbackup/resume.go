@@ -0,0 +1,194 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	klog "k8s.io/klog/v2"
+	"os"
+	"time"
+)
+
+// ResumeResult is the outcome of an async request reattached by Resume.
+type ResumeResult struct {
+	RequestId  string
+	Operation  string
+	Collection string
+	State      string
+	Err        error
+}
+
+// ResumeOptions controls which pending requests Resume reattaches to.
+type ResumeOptions struct {
+	// MinAge is the time since the last journal record of a request after which
+	// no running job polls it anymore, zero uses the operation timeout.
+	MinAge time.Duration
+}
+
+// abandoned tells whether no running job polls the request anymore: its job gave
+// up waiting, or it was last recorded before its job would have stopped waiting.
+// A job sharing the journal would otherwise see its request status deleted.
+func abandoned(config Config, opts ResumeOptions, entry JournalEntry, now time.Time) bool {
+	if entry.State == journalTimeout {
+		return true
+	}
+
+	minAge := opts.MinAge
+	if minAge <= 0 {
+		minAge = config.operationTimeout(entry.Operation)
+	}
+
+	return minAge > 0 && now.Sub(entry.Time) >= minAge
+}
+
+// pendingRequests returns async requests whose outcome was not checked or whose
+// status was not deleted at solr. Without a journal, request ids are listed from solr.
+func pendingRequests(ctx context.Context, client *Client) ([]JournalEntry, error) {
+	if client.Journal() == nil {
+		ids, err := client.ListRequestIds(ctx)
+
+		if err != nil {
+			return nil, fmt.Errorf("journal is disabled and request ids cannot be listed from solr: %w", err)
+		}
+
+		pending := make([]JournalEntry, 0, len(ids))
+		for _, id := range ids {
+			pending = append(pending, JournalEntry{RequestId: id, State: journalSubmitted})
+		}
+
+		return pending, nil
+	}
+
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make([]JournalEntry, 0)
+
+	for _, entry := range entries {
+		if entry.State != journalDeleted && entry.State != journalRejected {
+			pending = append(pending, entry)
+		}
+	}
+
+	return pending, nil
+}
+
+// resumeRequest waits for an unfinished request and deletes its status at solr.
+func resumeRequest(ctx context.Context, client *Client, config Config, entry JournalEntry) ResumeResult {
+	result := ResumeResult{RequestId: entry.RequestId, Operation: entry.Operation, Collection: entry.Collection, State: entry.State}
+
+	if entry.State == journalSubmitted || entry.State == journalTimeout {
+		err := waitRequestStatus(ctx, client, entry.RequestId, config.waitOptions(config.operationTimeout(entry.Operation)))
+
+		var taskErr *TaskFailedError
+		var timeoutErr *WaitTimeoutError
+
+		switch {
+		case err == nil:
+			result.State = journalCompleted
+		case errors.As(err, &taskErr):
+			result.State = taskErr.State
+			result.Err = err
+		case errors.As(err, &timeoutErr):
+			result.State = journalTimeout
+			result.Err = err
+
+			return result
+		default:
+			result.Err = err
+
+			return result
+		}
+	}
+
+	if result.State == journalNotFound {
+		client.journal.record(JournalEntry{RequestId: entry.RequestId, State: journalDeleted, Message: "not found at solr"})
+
+		return result
+	}
+
+	if err := deleteRequestId(ctx, client, entry.RequestId); err != nil && result.Err == nil {
+		result.Err = err
+	}
+
+	return result
+}
+
+// Resume reattaches to async requests left behind by an interrupted run, waits for
+// them to finish, reports their outcome and cleans up their status at solr. Journal
+// entries which a running job may still poll are skipped.
+func Resume(ctx context.Context, client *Client, config Config, opts ResumeOptions) error {
+	requests, err := pendingRequests(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	pending := make([]JournalEntry, 0, len(requests))
+
+	for _, entry := range requests {
+		if client.Journal() != nil && !abandoned(config, opts, entry, now) {
+			klog.Warningf("skipping %s %s of %s, it is %s since %s and may still be polled by a running job (see --min-age)", entry.RequestId, entry.Operation, entry.Collection, entry.State, entry.Time.Format(time.RFC3339))
+
+			continue
+		}
+
+		pending = append(pending, entry)
+	}
+
+	if len(pending) == 0 {
+		klog.V(1).Info("no pending request found")
+
+		return nil
+	}
+
+	t := prettytable.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(prettytable.Row{"Request Id", "Operation", "Collection", "State", "Error"})
+
+	failed := 0
+
+	for _, entry := range pending {
+		klog.V(1).Infof("resuming %s %s of %s", entry.RequestId, entry.Operation, entry.Collection)
+
+		result := resumeRequest(ctx, client, config, entry)
+
+		errMsg := ""
+		if result.Err != nil {
+			failed++
+			errMsg = result.Err.Error()
+		}
+
+		t.AppendRow(prettytable.Row{result.RequestId, result.Operation, result.Collection, result.State, errMsg})
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	t.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resumed requests failed", failed, len(pending))
+	}
+
+	return nil
+}
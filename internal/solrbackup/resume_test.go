@@ -0,0 +1,102 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var _ = Describe("Resume Tests", func() {
+	Context("Journal Resume Tests", func() {
+
+		var dir string
+		var server *httptest.Server
+		var deleted []string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+
+			deleted = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Query().Get("action") {
+				case "REQUESTSTATUS":
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":"found [%s] in completed tasks"}}`, r.URL.Query().Get("requestid"))
+				case "DELETESTATUS":
+					deleted = append(deleted, r.URL.Query().Get("requestid"))
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":"successfully removed stored response"}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		Describe("Test pending requests", func() {
+			It("Should be waited and cleaned up", func() {
+				config := Config{SolrEndpoint: server.URL, JournalFile: filepath.Join(dir, "journal.jsonl"), BackupTimeout: time.Hour, RestoreTimeout: time.Hour}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				old := time.Now().Add(-2 * time.Hour)
+
+				journal := client.Journal()
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", Operation: "backup", Collection: "test", State: journalSubmitted, Time: old})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-2", Operation: "backup", Collection: "test1", State: journalSubmitted, Time: old})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-2", State: journalDeleted, Time: old})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-3", Operation: "restore", Collection: "test", State: journalFailed, Time: old})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-4", Operation: "backup", Collection: "test", State: journalRejected, Time: old})).To(BeNil())
+
+				Expect(Resume(context.Background(), client, config, ResumeOptions{})).To(BeNil(), "Resume returns error")
+				Expect(deleted).To(Equal([]string{"sb-1", "sb-3"}))
+
+				pending, err := pendingRequests(context.Background(), client)
+				Expect(err).To(BeNil())
+				Expect(pending).To(BeEmpty())
+			})
+		})
+
+		Describe("Test requests of a running job", func() {
+			It("Should not be resumed before their operation timeout", func() {
+				config := Config{SolrEndpoint: server.URL, JournalFile: filepath.Join(dir, "journal.jsonl"), BackupTimeout: time.Hour}
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				journal := client.Journal()
+				Expect(journal.Record(JournalEntry{RequestId: "sb-1", Operation: "backup", Collection: "test", State: journalSubmitted})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-2", Operation: "backup", Collection: "test1", State: journalTimeout})).To(BeNil())
+				Expect(journal.Record(JournalEntry{RequestId: "sb-3", Operation: "restore", Collection: "test", State: journalSubmitted, Time: time.Now().Add(-2 * time.Hour)})).To(BeNil())
+
+				Expect(Resume(context.Background(), client, config, ResumeOptions{})).To(BeNil(), "Resume returns error")
+				Expect(deleted).To(Equal([]string{"sb-2"}), "restores waited forever need min age")
+
+				Expect(Resume(context.Background(), client, config, ResumeOptions{MinAge: time.Minute})).To(BeNil(), "Resume returns error")
+				Expect(deleted).To(Equal([]string{"sb-2", "sb-3"}))
+			})
+		})
+
+	})
+})
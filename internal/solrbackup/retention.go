@@ -0,0 +1,165 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy selects backup points to keep, the others are deleted. A backup
+// point is kept when any rule matches it.
+type RetentionPolicy struct {
+	// Days keeps backup points started within the last days.
+	Days int
+	// KeepLast keeps the newest backup points.
+	KeepLast int
+	// Daily, Weekly, Monthly and Yearly keep the newest backup point of that many
+	// latest days, ISO weeks, months and years which have backups.
+	Daily   int
+	Weekly  int
+	Monthly int
+	Yearly  int
+	// Minimum newest backup points are never deleted, even when all of them are
+	// older than every other rule.
+	Minimum int
+}
+
+// IsZero tells whether the policy keeps no backup points.
+func (p RetentionPolicy) IsZero() bool {
+	return p == RetentionPolicy{}
+}
+
+func (p RetentionPolicy) String() string {
+	rules := make([]string, 0)
+
+	add := func(name string, value int) {
+		if value > 0 {
+			rules = append(rules, fmt.Sprintf("%s=%d", name, value))
+		}
+	}
+	add("days", p.Days)
+	add("last", p.KeepLast)
+	add("daily", p.Daily)
+	add("weekly", p.Weekly)
+	add("monthly", p.Monthly)
+	add("yearly", p.Yearly)
+	add("minimum", p.Minimum)
+
+	if len(rules) == 0 {
+		return "none"
+	}
+
+	return strings.Join(rules, " ")
+}
+
+// RetentionDecision tells whether a backup point is kept and which rules keep it.
+type RetentionDecision struct {
+	Backup BackupPoint `json:"backup"`
+	Keep   bool        `json:"keep"`
+	Rules  []string    `json:"rules,omitempty"`
+}
+
+// Evaluate decides which backup points are kept at now. Decisions are ordered from
+// the newest backup point to the oldest.
+func (p RetentionPolicy) Evaluate(backups []BackupPoint, now time.Time) ([]RetentionDecision, error) {
+	decisions := make([]RetentionDecision, len(backups))
+	times := make([]time.Time, len(backups))
+
+	for i, backup := range backups {
+		t, err := backup.Time()
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse start time of backup %d: %v", backup.BackupId, err)
+		}
+
+		decisions[i] = RetentionDecision{Backup: backup}
+		times[i] = t.UTC()
+	}
+
+	order := make([]int, len(backups))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		ta, tb := times[order[a]], times[order[b]]
+		if ta.Equal(tb) {
+			return backups[order[a]].BackupId > backups[order[b]].BackupId
+		}
+		return ta.After(tb)
+	})
+
+	keep := func(i int, rule string) {
+		decisions[i].Keep = true
+		decisions[i].Rules = append(decisions[i].Rules, rule)
+	}
+
+	periods := []struct {
+		rule  string
+		count int
+		key   func(t time.Time) string
+	}{
+		{"daily", p.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", p.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{"monthly", p.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+		{"yearly", p.Yearly, func(t time.Time) string { return t.Format("2006") }},
+	}
+
+	cutoff := now.AddDate(0, 0, -p.Days)
+
+	for n, i := range order {
+		if n < p.Minimum {
+			keep(i, "minimum")
+		}
+
+		if n < p.KeepLast {
+			keep(i, "last")
+		}
+
+		if p.Days > 0 && !times[i].Before(cutoff) {
+			keep(i, fmt.Sprintf("within %d days", p.Days))
+		}
+	}
+
+	for _, period := range periods {
+		seen := make(map[string]bool)
+
+		for _, i := range order {
+			if len(seen) >= period.count {
+				break
+			}
+
+			key := period.key(times[i])
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			keep(i, period.rule)
+		}
+	}
+
+	sorted := make([]RetentionDecision, 0, len(order))
+	for _, i := range order {
+		sorted = append(sorted, decisions[i])
+	}
+
+	return sorted, nil
+}
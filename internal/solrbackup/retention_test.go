@@ -0,0 +1,101 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"time"
+)
+
+var _ = Describe("Retention Tests", func() {
+	Context("Retention Policy Tests", func() {
+
+		now := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+
+		// two backups a day for 400 days, oldest first like LISTBACKUP
+		backups := make([]BackupPoint, 0)
+		for day := 399; day >= 0; day-- {
+			for _, hour := range []int{1, 13} {
+				t := now.AddDate(0, 0, -day).Truncate(24 * time.Hour).Add(time.Duration(hour) * time.Hour)
+				if t.After(now) {
+					continue
+				}
+				backups = append(backups, BackupPoint{BackupId: int64(len(backups)), StartTime: t.Format(time.RFC3339Nano)})
+			}
+		}
+
+		kept := func(policy RetentionPolicy) map[string][]string {
+			decisions, err := policy.Evaluate(backups, now)
+			Expect(err).To(BeNil(), "Evaluate returns error")
+			Expect(decisions).To(HaveLen(len(backups)))
+
+			result := make(map[string][]string)
+			for _, decision := range decisions {
+				if decision.Keep {
+					result[decision.Backup.StartTime] = decision.Rules
+				}
+			}
+			return result
+		}
+
+		Describe("Test keep last", func() {
+			It("Should keep newest backup points", func() {
+				Expect(kept(RetentionPolicy{KeepLast: 2})).To(Equal(map[string][]string{
+					"2022-10-15T01:00:00Z": {"last"},
+					"2022-10-14T13:00:00Z": {"last"},
+				}))
+			})
+		})
+
+		Describe("Test days", func() {
+			It("Should keep backup points started within days", func() {
+				Expect(kept(RetentionPolicy{Days: 1})).To(HaveLen(2))
+			})
+		})
+
+		Describe("Test grandfather-father-son", func() {
+			It("Should keep newest backup point of each period", func() {
+				result := kept(RetentionPolicy{Daily: 3, Weekly: 2, Monthly: 2, Yearly: 2})
+				Expect(result).To(Equal(map[string][]string{
+					"2022-10-15T01:00:00Z": {"daily", "weekly", "monthly", "yearly"},
+					"2022-10-14T13:00:00Z": {"daily"},
+					"2022-10-13T13:00:00Z": {"daily"},
+					"2022-10-09T13:00:00Z": {"weekly"},
+					"2022-09-30T13:00:00Z": {"monthly"},
+					"2021-12-31T13:00:00Z": {"yearly"},
+				}))
+			})
+		})
+
+		Describe("Test minimum", func() {
+			It("Should keep newest backup points even when all are old", func() {
+				decisions, err := RetentionPolicy{Days: 7, Minimum: 1}.Evaluate(backups[:3], now)
+				Expect(err).To(BeNil(), "Evaluate returns error")
+				Expect(decisions[0].Keep).To(BeTrue())
+				Expect(decisions[0].Rules).To(Equal([]string{"minimum"}))
+				Expect(decisions[1].Keep).To(BeFalse())
+				Expect(decisions[2].Keep).To(BeFalse())
+			})
+		})
+
+		Describe("Test invalid start time", func() {
+			It("Should return error", func() {
+				_, err := RetentionPolicy{KeepLast: 1}.Evaluate([]BackupPoint{{BackupId: 0, StartTime: "yesterday"}}, now)
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+	})
+})
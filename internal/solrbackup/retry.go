@@ -0,0 +1,116 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"io"
+	klog "k8s.io/klog/v2"
+	"math/rand"
+	"net"
+	"net/url"
+	"time"
+)
+
+// RetryPolicy controls how failed solr requests are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes each backoff by the given fraction, e.g. 0.2 is ±20%.
+	Jitter               float64
+	RetryableStatusCodes []int
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:          5,
+		InitialBackoff:       time.Second,
+		MaxBackoff:           30 * time.Second,
+		Multiplier:           2,
+		Jitter:               0.2,
+		RetryableStatusCodes: []int{429, 502, 503, 504},
+	}
+}
+
+// backoff returns the wait duration before the given retry, starting from 1.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := float64(p.InitialBackoff)
+
+	for i := 1; i < retry; i++ {
+		d *= p.Multiplier
+		if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+			d = float64(p.MaxBackoff)
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// retryable reports whether err is a transient failure worth retrying.
+func (p RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var solrErr *SolrError
+	if errors.As(err, &solrErr) {
+		for _, code := range p.RetryableStatusCodes {
+			if solrErr.HTTPStatus == code {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retry calls fn until it succeeds, fails with a permanent error, attempts are
+// exhausted or ctx is done.
+func (p RetryPolicy) retry(ctx context.Context, name string, fn func() error) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+
+		d := p.backoff(attempt)
+		klog.Warningf("%s failed, retrying in %v (attempt %d/%d): %v", name, d, attempt, p.MaxAttempts, err)
+
+		if err := sleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+var _ = Describe("Retry Policy Tests", func() {
+	Context("Backoff Tests", func() {
+
+		policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: 4 * time.Second, Multiplier: 2}
+
+		Describe("Test exponential backoff", func() {
+			It("Should grow up to max backoff", func() {
+				Expect(policy.backoff(1)).To(Equal(time.Second))
+				Expect(policy.backoff(2)).To(Equal(2 * time.Second))
+				Expect(policy.backoff(3)).To(Equal(4 * time.Second))
+				Expect(policy.backoff(10)).To(Equal(4 * time.Second))
+			})
+
+			It("Jitter should stay in bounds", func() {
+				jittered := policy
+				jittered.Jitter = 0.5
+				for i := 0; i < 100; i++ {
+					d := jittered.backoff(2)
+					Expect(d).To(BeNumerically(">=", time.Second))
+					Expect(d).To(BeNumerically("<=", 3*time.Second))
+				}
+			})
+		})
+
+		Describe("Test retryable errors", func() {
+			policy := DefaultRetryPolicy()
+
+			It("Unavailable solr should be retried", func() {
+				Expect(policy.retryable(&SolrError{HTTPStatus: 503})).To(BeTrue())
+			})
+
+			It("Bad request should not be retried", func() {
+				Expect(policy.retryable(&SolrError{HTTPStatus: 400})).To(BeFalse())
+			})
+
+			It("Canceled context should not be retried", func() {
+				Expect(policy.retryable(fmt.Errorf("get: %w", context.Canceled))).To(BeFalse())
+			})
+
+			It("Unknown errors should not be retried", func() {
+				Expect(policy.retryable(errors.New("unknown"))).To(BeFalse())
+			})
+		})
+	})
+
+	Context("Async Submission Tests", func() {
+
+		newClient := func(handler http.HandlerFunc) (*Client, *httptest.Server) {
+			server := httptest.NewServer(handler)
+			retry := DefaultRetryPolicy()
+			retry.InitialBackoff = time.Millisecond
+			client, err := NewClient(Config{SolrEndpoint: server.URL, Location: "/", Retry: retry})
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return client, server
+		}
+
+		Describe("Test unregistered request", func() {
+			It("Submission should be retried", func() {
+				backups := 0
+				client, server := newClient(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Query().Get("action") {
+					case "BACKUP":
+						backups++
+						if backups == 1 {
+							w.WriteHeader(http.StatusServiceUnavailable)
+							return
+						}
+						fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"requestid":"sb-1"}`)
+					case "REQUESTSTATUS":
+						fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"notfound","msg":"Did not find [sb-1] in any tasks queue"}}`)
+					}
+				})
+				defer server.Close()
+
+				err := client.Backup(context.Background(), "sb-1", BackupParams{Collection: "test", Name: "test"})
+				Expect(err).To(BeNil(), "Backup returns error")
+				Expect(backups).To(Equal(2))
+			})
+		})
+
+		Describe("Test registered request", func() {
+			It("Submission should not be retried", func() {
+				backups := 0
+				client, server := newClient(func(w http.ResponseWriter, r *http.Request) {
+					switch r.URL.Query().Get("action") {
+					case "BACKUP":
+						backups++
+						w.WriteHeader(http.StatusBadGateway)
+					case "REQUESTSTATUS":
+						fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"running","msg":"found [sb-1] in running tasks"}}`)
+					}
+				})
+				defer server.Close()
+
+				err := client.Backup(context.Background(), "sb-1", BackupParams{Collection: "test", Name: "test"})
+				Expect(err).To(BeNil(), "Backup returns error")
+				Expect(backups).To(Equal(1))
+			})
+		})
+
+	})
+})
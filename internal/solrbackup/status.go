@@ -0,0 +1,147 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	prettytable "github.com/jedib0t/go-pretty/v6/table"
+	klog "k8s.io/klog/v2"
+	"os"
+	"sort"
+	"time"
+)
+
+type StatusGCOptions struct {
+	// OlderThan is the minimum age of a finished request status to be deleted.
+	OlderThan time.Duration
+	DryRun    bool
+}
+
+// ownedRequestIds returns async request ids of this tool from solr and the journal.
+// Listing from solr is not supported by all solr versions, so the journal is used
+// alone when it fails.
+func ownedRequestIds(ctx context.Context, client *Client) (map[string]JournalEntry, error) {
+	ids := make(map[string]JournalEntry)
+
+	listed, listErr := client.ListRequestIds(ctx)
+	if listErr != nil {
+		klog.Warningf("cannot list request ids from solr: %v", listErr)
+	}
+
+	for _, id := range listed {
+		ids[id] = JournalEntry{RequestId: id}
+	}
+
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.State != journalDeleted && entry.State != journalRejected {
+			ids[entry.RequestId] = entry
+		}
+	}
+
+	if listErr != nil && client.Journal() == nil {
+		return nil, fmt.Errorf("journal is disabled and request ids cannot be listed from solr: %w", listErr)
+	}
+
+	return ids, nil
+}
+
+// StatusGC deletes stored statuses of finished async requests of this tool which
+// are older than opts.OlderThan, and reports all of them with their state and age.
+func StatusGC(ctx context.Context, client *Client, opts StatusGCOptions) error {
+	owned, err := ownedRequestIds(ctx, client)
+
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(owned))
+	for id := range owned {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	t := prettytable.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(prettytable.Row{"Request Id", "Operation", "Collection", "State", "Age", "Action"})
+
+	now := time.Now()
+	failed := 0
+
+	for _, id := range ids {
+		entry := owned[id]
+
+		resp, err := client.RequestStatus(ctx, id)
+
+		if err != nil {
+			failed++
+			t.AppendRow(prettytable.Row{id, entry.Operation, entry.Collection, "", "", fmt.Sprintf("error: %v", err)})
+
+			continue
+		}
+
+		state := resp.Status.State
+
+		created, ok := requestIdTime(id)
+		if !ok {
+			created = entry.Time
+		}
+
+		age := ""
+		if !created.IsZero() {
+			age = now.Sub(created).Round(time.Second).String()
+		}
+
+		action := "keep"
+
+		switch {
+		case state == "notfound":
+			action = "none"
+			if !opts.DryRun {
+				client.journal.record(JournalEntry{RequestId: id, State: journalDeleted, Message: "not found at solr"})
+			}
+		case state != "completed" && state != "failed":
+			action = "keep (" + state + ")"
+		case created.IsZero():
+			action = "keep (unknown age)"
+		case now.Sub(created) < opts.OlderThan:
+			action = "keep (recent)"
+		case opts.DryRun:
+			action = "delete (dry run)"
+		default:
+			if err := deleteRequestId(ctx, client, id); err != nil {
+				failed++
+				action = fmt.Sprintf("delete failed: %v", err)
+			} else {
+				action = "deleted"
+			}
+		}
+
+		t.AppendRow(prettytable.Row{id, entry.Operation, entry.Collection, state, age, action})
+	}
+
+	t.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d request statuses could not be collected", failed, len(ids))
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+var _ = Describe("Status GC Tests", func() {
+	Context("Stale Status Tests", func() {
+
+		oldId := "sb-test-" + newULID(time.Now().Add(-48*time.Hour))
+		recentId := "sb-test-" + newULID(time.Now())
+		runningId := "sb-test-" + newULID(time.Now().Add(-48*time.Hour))
+
+		var deleted []string
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			deleted = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id := r.URL.Query().Get("requestid")
+				switch r.URL.Query().Get("action") {
+				case "REQUESTSTATUS":
+					if id == "" {
+						fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"requestids":["%s","%s","%s","1000"]}`, oldId, recentId, runningId)
+						return
+					}
+					state := "completed"
+					if id == runningId {
+						state = "running"
+					}
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"%s","msg":""}}`, state)
+				case "DELETESTATUS":
+					deleted = append(deleted, id)
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":"successfully removed stored response"}`)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Describe("Test gc", func() {
+			It("Should only delete old finished statuses", func() {
+				client, err := NewClient(Config{SolrEndpoint: server.URL})
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = StatusGC(context.Background(), client, StatusGCOptions{OlderThan: 24 * time.Hour})
+				Expect(err).To(BeNil(), "StatusGC returns error")
+				Expect(deleted).To(Equal([]string{oldId}))
+			})
+
+			It("Dry run should not delete", func() {
+				client, err := NewClient(Config{SolrEndpoint: server.URL})
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = StatusGC(context.Background(), client, StatusGCOptions{OlderThan: 24 * time.Hour, DryRun: true})
+				Expect(err).To(BeNil(), "StatusGC returns error")
+				Expect(deleted).To(BeEmpty())
+			})
+		})
+
+	})
+})
@@ -0,0 +1,61 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+)
+
+var _ = Describe("TLS Config Tests", func() {
+	Context("New TLS Config Tests", func() {
+
+		Describe("Test defaults", func() {
+			It("Should return nil config", func() {
+				tlsConfig, err := newTLSConfig(Config{})
+				Expect(err).To(BeNil())
+				Expect(tlsConfig).To(BeNil())
+			})
+		})
+
+		Describe("Test server name and insecure", func() {
+			It("Should be applied", func() {
+				tlsConfig, err := newTLSConfig(Config{ServerName: "solr.svc", InsecureSkipVerify: true})
+				Expect(err).To(BeNil())
+				Expect(tlsConfig.ServerName).To(Equal("solr.svc"))
+				Expect(tlsConfig.InsecureSkipVerify).To(BeTrue())
+			})
+		})
+
+		Describe("Test invalid files", func() {
+			It("Client certificate without key should fail", func() {
+				_, err := newTLSConfig(Config{CertFile: "tls.crt"})
+				Expect(err).NotTo(BeNil())
+			})
+
+			It("CA file without certificates should fail", func() {
+				f, err := ioutil.TempFile("", "ca")
+				Expect(err).To(BeNil(), "cannot create temp file")
+				defer os.Remove(f.Name())
+				f.Close()
+
+				_, err = newTLSConfig(Config{CAFile: f.Name()})
+				Expect(err).NotTo(BeNil())
+			})
+		})
+
+	})
+})
@@ -14,93 +14,116 @@ limitations under the License.
 package solrbackup
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	klog "k8s.io/klog/v2"
-	"net/http"
 	"time"
 )
 
 const (
-	collection_api string = "/solr/admin/collections"
+	collection_api      string = "/solr/admin/collections"
+	defaultPollInterval        = 5 * time.Second
 )
 
-func sendRequest(uri string) (map[string]interface{}, error) {
-	resp, err := http.Get(uri)
+// sleepContext waits for the duration or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
 
-	if err != nil {
-		klog.Errorf("error while get reqeust: %v", err)
-
-		return nil, err
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
+}
 
-	defer resp.Body.Close()
+func waitRequestStatus(ctx context.Context, client *Client, reqId string, opts WaitOptions) error {
+	_, err := waitRequestResult(ctx, client, reqId, opts)
 
-	body, err := ioutil.ReadAll(resp.Body)
+	return err
+}
 
-	if err != nil {
-		klog.Error("error while reading response: %v", err)
+// waitRequestResult waits until the async request completes and returns its final
+// status, which carries the operation specific results.
+func waitRequestResult(ctx context.Context, client *Client, reqId string, opts WaitOptions) (*RequestStatusResponse, error) {
+	start := time.Now()
+	parent := ctx
 
-		return nil, err
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
 	}
 
-	klog.V(5).Infof("body: %v", string(body))
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
 
-	var resp_json map[string]interface{}
+	lastState := "unknown"
 
-	if err := json.Unmarshal(body, &resp_json); err != nil {
-		klog.Errorf("error while reading response: %v", err)
+	timedOut := func(err error) error {
+		if errors.Is(err, context.DeadlineExceeded) && parent.Err() == nil {
+			client.journal.record(JournalEntry{RequestId: reqId, State: journalTimeout, Message: lastState})
 
-		return nil, err
-	}
+			return &WaitTimeoutError{RequestId: reqId, LastState: lastState, Elapsed: time.Since(start)}
+		}
 
-	return resp_json, nil
-}
+		return err
+	}
 
-func waitRequestStatus(config Config, reqId int64) error {
 	for {
-		reqstatus_uri := fmt.Sprintf("%s%s?action=REQUESTSTATUS&requestid=sb-%d", config.SolrEndpoint, collection_api, reqId)
-		klog.V(5).Infof("wrs uri: %v", reqstatus_uri)
-
-		resp, err := sendRequest(reqstatus_uri)
+		resp, err := client.RequestStatus(ctx, reqId)
 
 		if err != nil {
 			klog.Errorf("error: %v", err)
 
-			return err
+			return nil, timedOut(err)
 		}
 
-		klog.V(5).Infof("request status response %v", resp)
-
-		state, _ := resp["status"].(map[string]interface{})["state"]
+		state := resp.Status.State
+		lastState = state
 
 		if state == "running" || state == "submitted" {
-			time.Sleep(time.Second * 5)
+			klog.V(5).Infof("request %s is %s for %v", reqId, state, time.Since(start).Round(time.Second))
+
+			if err := sleepContext(ctx, interval); err != nil {
+				return nil, timedOut(err)
+			}
+
+			if opts.PollBackoff > 1 {
+				interval = time.Duration(float64(interval) * opts.PollBackoff)
+				if opts.MaxPollInterval > 0 && interval > opts.MaxPollInterval {
+					interval = opts.MaxPollInterval
+				}
+			}
 			continue
 		} else if state == "completed" {
-			break
+			client.journal.record(JournalEntry{RequestId: reqId, State: journalCompleted})
+
+			return resp, nil
+		} else if state == "failed" || state == "notfound" {
+			taskErr := newTaskFailedError(reqId, resp)
+			klog.Errorf("error: %v", taskErr)
+			client.journal.record(JournalEntry{RequestId: reqId, State: state, Message: taskErr.Error()})
+
+			return nil, taskErr
 		} else {
-			return fmt.Errorf("unknown state: %v", state)
+			return nil, fmt.Errorf("unknown state: %v", state)
 		}
 	}
-
-	return nil
 }
 
-func deleteRequestId(config Config, reqId int64) error {
-	delete_uri := fmt.Sprintf("%s%s?action=DELETESTATUS&requestid=sb-%d", config.SolrEndpoint, collection_api, reqId)
-	klog.V(5).Infof("delete uri: %v", delete_uri)
-
-	resp, err := sendRequest(delete_uri)
-
-	if err != nil {
+func deleteRequestId(ctx context.Context, client *Client, reqId string) error {
+	if err := client.DeleteStatus(ctx, reqId); err != nil {
 		klog.Errorf("error: %v", err)
 
 		return err
 	}
 
-	klog.V(5).Infof("delete response %v", resp)
+	client.journal.record(JournalEntry{RequestId: reqId, State: journalDeleted})
 
 	return nil
 }
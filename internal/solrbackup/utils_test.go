@@ -0,0 +1,80 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+var _ = Describe("Wait Request Status Tests", func() {
+	Context("Timeout Tests", func() {
+
+		Describe("Test hung request", func() {
+			It("Should return timeout error with last state", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"running","msg":"found [sb-1] in running tasks"}}`)
+				}))
+				defer server.Close()
+
+				client, err := NewClient(Config{SolrEndpoint: server.URL})
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				opts := WaitOptions{Timeout: 100 * time.Millisecond, PollInterval: 10 * time.Millisecond, MaxPollInterval: 20 * time.Millisecond, PollBackoff: 2}
+				err = waitRequestStatus(context.Background(), client, "sb-1", opts)
+
+				var timeoutErr *WaitTimeoutError
+				Expect(errors.As(err, &timeoutErr)).To(BeTrue(), "error is not WaitTimeoutError")
+				Expect(timeoutErr.LastState).To(Equal("running"))
+				Expect(timeoutErr.Elapsed).To(BeNumerically(">=", 100*time.Millisecond))
+			})
+		})
+
+		Describe("Test completed request", func() {
+			It("Should return nil", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":"found [sb-1] in completed tasks"}}`)
+				}))
+				defer server.Close()
+
+				client, err := NewClient(Config{SolrEndpoint: server.URL})
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				Expect(waitRequestStatus(context.Background(), client, "sb-1", WaitOptions{Timeout: time.Second})).To(BeNil())
+			})
+		})
+
+	})
+
+	Context("Operation Timeout Tests", func() {
+
+		Describe("Test journaled operations", func() {
+			It("Should map every async operation to its timeout", func() {
+				config := Config{BackupTimeout: time.Hour, RestoreTimeout: 2 * time.Hour, DeleteTimeout: 3 * time.Hour}
+
+				Expect(config.operationTimeout("backup")).To(Equal(time.Hour))
+				Expect(config.operationTimeout("restore")).To(Equal(2 * time.Hour))
+				Expect(config.operationTimeout("deletebackup")).To(Equal(3 * time.Hour))
+				Expect(config.operationTimeout("delete")).To(Equal(3 * time.Hour))
+			})
+		})
+
+	})
+})
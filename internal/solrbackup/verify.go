@@ -0,0 +1,178 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	klog "k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+// QueryExpectation is a query and the number of documents it should match.
+type QueryExpectation struct {
+	Query string
+	Count int64
+}
+
+// VerifyOptions controls checks of a restored collection.
+type VerifyOptions struct {
+	// Queries must match exactly their expected number of documents.
+	Queries []QueryExpectation
+	// ReadyTimeout is the max wait for all replicas to become active, 0 checks
+	// them only once.
+	ReadyTimeout time.Duration
+	// CountTolerance is the max difference between the document count of the
+	// collection and the count recorded when the backup point was taken.
+	CountTolerance int64
+}
+
+// recordedDocuments returns the document count recorded in the journal when the
+// backup point was taken, or nil when it is unknown. A nil backupId selects the
+// latest backup point of the name.
+func recordedDocuments(client *Client, name string, backupId *int64) (*int64, error) {
+	entries, err := client.Journal().Entries()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var selected *JournalEntry
+
+	for i, entry := range entries {
+		if entry.Operation != "backup" || backupBaseName(entry.Name) != name || entry.Outcome != journalCompleted || entry.Documents == nil || entry.BackupId == nil {
+			continue
+		}
+
+		if backupId != nil {
+			if *entry.BackupId == *backupId {
+				selected = &entries[i]
+			}
+		} else if selected == nil || *entry.BackupId >= *selected.BackupId {
+			selected = &entries[i]
+		}
+	}
+
+	if selected == nil {
+		return nil, nil
+	}
+
+	return selected.Documents, nil
+}
+
+// latestBackupId returns the id of the newest backup point of the collection.
+func latestBackupId(ctx context.Context, client *Client, config Config, colId int64) (*int64, error) {
+	backups, err := backupListRetrive(ctx, client, config, colId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *int64
+
+	for i, backup := range backups {
+		if latest == nil || backup.BackupId > *latest {
+			latest = &backups[i].BackupId
+		}
+	}
+
+	return latest, nil
+}
+
+// Verify checks that every shard of the collection has an active leader and all
+// replicas are active, that it has as many documents as were counted when the
+// backup point was taken and that the given queries match their expected counts.
+// A nil backupId compares against the latest backup point.
+func Verify(ctx context.Context, client *Client, config Config, colId int64, collection string, backupId *int64, opts VerifyOptions) error {
+	name := config.Collections[colId]
+
+	if client.Journal() == nil {
+		return errors.New("document counts of backups are recorded in the journal, verification requires a journal")
+	}
+	problems := make([]string, 0)
+
+	var err error
+
+	if opts.ReadyTimeout > 0 {
+		err = waitCollectionActive(ctx, client, collection, config.waitOptions(opts.ReadyTimeout))
+	} else {
+		err = checkCollectionHealth(ctx, client, collection)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+
+		problems = append(problems, err.Error())
+	}
+
+	count, err := client.Count(ctx, collection, "*:*")
+
+	if err != nil {
+		klog.Errorf("error: %v", err)
+
+		return err
+	}
+
+	klog.V(1).Infof("collection %s has %d documents", collection, count)
+
+	if backupId == nil {
+		if backupId, err = latestBackupId(ctx, client, config, colId); err != nil {
+			return err
+		}
+	}
+
+	recorded, err := recordedDocuments(client, name, backupId)
+
+	if err != nil {
+		return err
+	}
+
+	if recorded == nil {
+		klog.Warningf("document count of backup %s is not recorded in the journal, skipping count comparison", name)
+	} else if diff := count - *recorded; diff > opts.CountTolerance || -diff > opts.CountTolerance {
+		problems = append(problems, fmt.Sprintf("collection has %d documents, backup had %d", count, *recorded))
+	}
+
+	for _, expected := range opts.Queries {
+		matched, err := client.Count(ctx, collection, expected.Query)
+
+		if err != nil {
+			klog.Errorf("error: %v", err)
+
+			return err
+		}
+
+		if matched != expected.Count {
+			problems = append(problems, fmt.Sprintf("query %q matched %d documents, expected %d", expected.Query, matched, expected.Count))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("verification of %s failed: %s", collection, strings.Join(problems, "; "))
+	}
+
+	klog.V(0).Infof("collection %s is verified", collection)
+
+	return nil
+}
+
+func VerifyAll(ctx context.Context, client *Client, config Config, opts VerifyOptions) error {
+	return forEachCollection(ctx, config, "verify", func(colId int64) error {
+		return Verify(ctx, client, config, colId, config.collectionName(colId), nil, opts)
+	})
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2022 Mantis Software
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+   http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package solrbackup
+
+import (
+	"context"
+	"fmt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var _ = Describe("Verify Tests", func() {
+	Context("Restored Collection Verification Tests", func() {
+
+		var dir string
+		var server *httptest.Server
+		var mu sync.Mutex
+		var numFound map[string]int
+		var replicaState string
+		var indexedDuringBackup int
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "solr-backup")
+			Expect(err).To(BeNil(), "cannot create temp dir")
+
+			numFound, replicaState, indexedDuringBackup = map[string]int{"*:*": 10, "type:book": 4}, "active", 0
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				q := r.URL.Query()
+
+				if r.URL.Path == "/solr/products/select" {
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"response":{"numFound":%d,"start":0,"docs":[]}}`, numFound[q.Get("q")])
+					return
+				}
+
+				switch q.Get("action") {
+				case "BACKUP":
+					numFound["*:*"] += indexedDuringBackup
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "DELETESTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1}}`)
+				case "REQUESTSTATUS":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"status":{"state":"completed","msg":""}}`)
+				case "LISTBACKUP":
+					fmt.Fprint(w, `{"responseHeader":{"status":0,"QTime":1},"collection":"products","backups":[{"backupId":1,"startTime":"2022-10-01T00:00:00Z"},{"backupId":2,"startTime":"2022-10-02T00:00:00Z"}]}`)
+				case "CLUSTERSTATUS":
+					fmt.Fprintf(w, `{"responseHeader":{"status":0,"QTime":1},"cluster":{"collections":{"products":{"shards":{"shard1":{"state":"active","replicas":{"core_node1":{"node_name":"n1:8983_solr","state":"active","leader":"true"},"core_node2":{"node_name":"n1:8983_solr","state":"%s","leader":"false"}}}}}},"live_nodes":["n1:8983_solr"]}}`, replicaState)
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			os.RemoveAll(dir)
+		})
+
+		newClient := func() (Config, *Client) {
+			config := Config{SolrEndpoint: server.URL, Location: "/", Collections: []string{"products"}, JournalFile: filepath.Join(dir, "journal.jsonl")}
+			client, err := NewClient(config)
+			Expect(err).To(BeNil(), "NewClient returns error")
+			return config, client
+		}
+
+		Describe("Test backup records document count", func() {
+			It("Should record count and backup point to journal", func() {
+				config, client := newClient()
+
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				entries, err := client.Journal().Entries()
+				Expect(err).To(BeNil(), "Entries returns error")
+				Expect(entries).To(HaveLen(1))
+				Expect(*entries[0].Documents).To(Equal(int64(10)))
+				Expect(*entries[0].BackupId).To(Equal(int64(2)))
+				Expect(entries[0].State).To(Equal(journalDeleted))
+			})
+		})
+
+		Describe("Test backup of collection indexed during backup", func() {
+			It("Should not record count", func() {
+				config, client := newClient()
+				indexedDuringBackup = 3
+
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				entries, err := client.Journal().Entries()
+				Expect(err).To(BeNil(), "Entries returns error")
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Documents).To(BeNil())
+				Expect(*entries[0].BackupId).To(Equal(int64(2)))
+			})
+		})
+
+		Describe("Test successful verification", func() {
+			It("Should be succeed", func() {
+				config, client := newClient()
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				opts := VerifyOptions{Queries: []QueryExpectation{{Query: "type:book", Count: 4}}}
+				Expect(Verify(context.Background(), client, config, 0, "products", nil, opts)).To(BeNil())
+			})
+		})
+
+		Describe("Test failed verification", func() {
+			It("Should report count mismatch, inactive replicas and query mismatch", func() {
+				config, client := newClient()
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				mu.Lock()
+				numFound["*:*"] = 7
+				replicaState = "recovering"
+				mu.Unlock()
+
+				opts := VerifyOptions{Queries: []QueryExpectation{{Query: "type:book", Count: 5}}}
+				err := Verify(context.Background(), client, config, 0, "products", nil, opts)
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("core_node2 is recovering"))
+				Expect(err.Error()).To(ContainSubstring("collection has 7 documents, backup had 10"))
+				Expect(err.Error()).To(ContainSubstring(`query "type:book" matched 4 documents, expected 5`))
+			})
+		})
+
+		Describe("Test verification with count tolerance", func() {
+			It("Should accept difference within tolerance only", func() {
+				config, client := newClient()
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				mu.Lock()
+				numFound["*:*"] = 12
+				mu.Unlock()
+
+				Expect(Verify(context.Background(), client, config, 0, "products", nil, VerifyOptions{CountTolerance: 2})).To(BeNil())
+
+				err := Verify(context.Background(), client, config, 0, "products", nil, VerifyOptions{CountTolerance: 1})
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("collection has 12 documents, backup had 10"))
+			})
+		})
+
+		Describe("Test verification without journal", func() {
+			It("Should return error", func() {
+				config, _ := newClient()
+				config.JournalFile = ""
+				client, err := NewClient(config)
+				Expect(err).To(BeNil(), "NewClient returns error")
+
+				err = Verify(context.Background(), client, config, 0, "products", nil, VerifyOptions{})
+				Expect(err).To(MatchError(ContainSubstring("requires a journal")))
+			})
+		})
+
+		Describe("Test verification of unrecorded backup point", func() {
+			It("Should skip count comparison", func() {
+				config, client := newClient()
+				Expect(Backup(context.Background(), client, config, 0)).To(BeNil(), "Backup returns error")
+
+				mu.Lock()
+				numFound["*:*"] = 7
+				mu.Unlock()
+
+				backupId := int64(1)
+				Expect(Verify(context.Background(), client, config, 0, "products", &backupId, VerifyOptions{})).To(BeNil())
+			})
+		})
+
+	})
+})